@@ -16,10 +16,14 @@
 package changes
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"regexp"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/ben-clayton/release-me/semver"
 )
@@ -28,25 +32,51 @@ import (
 type Content struct {
 	versions []version
 	lines    []string
+	crlf     bool // True if the lines of the file are terminated with CRLF
 }
 
 type version struct {
 	semver.Version
-	line   int    // Line number this was found on
-	prefix string // Prefix before the semver
-	style  semver.Style
-	sep    string // Separator between version and date
-	date   string // Date after the semver
+	line      int    // Line number this was found on
+	prefix    string // Prefix before the semver
+	style     semver.Style
+	sep       string // Separator between version and date
+	date      string // Date after the semver
+	anchorSep string // Separator between the date and custom anchor
+	anchor    string // Custom heading anchor (e.g. '{#v1.2.3}')
+	underline byte   // Setext heading underline character ('=' or '-'), or 0
 }
 
+// maxLineLength is the maximum length of a single line read by ReadTop() and
+// ReadUntil().
+const maxLineLength = 1024 * 1024
+
 var (
+	// breakingChangeRE matches the markers used to document a breaking change
+	// in the release notes: 'BREAKING:', 'BREAKING CHANGE:', 'BREAKING CHANGES:'
+	// or '⚠' at the start of a line or list item.
+	breakingChangeRE = regexp.MustCompile(`(?m)^\s*(?:[*+-]\s+)?(?:BREAKING(?: CHANGES?)?:|⚠)`)
+
 	// changesVersionRE is the regular expression used to parse versions from a CHANGES file.
-	changesVersionRE = regexp.MustCompile(`^(#* *)((?:\w*-|v)?\d+\.\d+(?:\.\d+)?(?:-\w+)?)( *)(\d\d\d\d-\d\d-\d\d)? *$`)
+	changesVersionRE = regexp.MustCompile(`^(#* *)((?:\d+:)?(?:\w*-|v)?\d+\.\d+(?:(?:\.\d+){1,2}(?:-\w+|[_.]?[A-Za-z]\w*)?|(?:-\w+|_?[A-Za-z]\w*)?)(?:\+[0-9A-Za-z.-]+)?)( *)(\d\d\d\d-\d\d-\d\d)?( *)(\{#[^}]*\})? *$`)
+
+	// underlineRE is the regular expression used to match setext heading
+	// underlines.
+	underlineRE = regexp.MustCompile(`^(=+|-+) *$`)
+
+	// linkRE is the regular expression used to find intra-document links.
+	linkRE = regexp.MustCompile(`\]\(#([^)]*)\)`)
 )
 
 // Read parses the content of the CHANGES file from body, returning a Content.
+// If body uses CRLF line endings, then these are preserved by String().
 func Read(body string) (*Content, error) {
-	c := Content{lines: strings.Split(body, "\n")}
+	c := Content{}
+	if strings.Contains(body, "\r\n") {
+		c.crlf = true
+		body = strings.ReplaceAll(body, "\r\n", "\n")
+	}
+	c.lines = strings.Split(body, "\n")
 	if err := c.parse(); err != nil {
 		return nil, err
 	}
@@ -55,28 +85,112 @@ func Read(body string) (*Content, error) {
 
 func (c *Content) parse() error {
 	for i, line := range c.lines {
-		m := changesVersionRE.FindStringSubmatch(line)
-		if len(m) == 0 {
+		if c.parseUnderline(i, line) {
 			continue
 		}
-		var err error
-		v := version{line: i + 1}
-		v.prefix = m[1]
-		v.Version, err = semver.Parse(m[2])
+		v, ok, err := parseVersion(i, line)
 		if err != nil {
-			return fmt.Errorf("%v on line %v", err, i)
+			return err
 		}
-		if s := semver.ParseStyle(m[2]); s != nil {
-			v.style = *s
+		if ok {
+			c.versions = append(c.versions, v)
 		}
-		v.sep = m[3]
-		v.date = m[4]
-		c.versions = append(c.versions, v)
 	}
 	return nil
 }
 
+// parseVersion attempts to parse a version heading from the line with the
+// 0-based index i.
+func parseVersion(i int, line string) (version, bool, error) {
+	m := changesVersionRE.FindStringSubmatch(line)
+	if len(m) == 0 {
+		return version{}, false, nil
+	}
+	var err error
+	v := version{line: i + 1}
+	v.prefix = m[1]
+	v.Version, err = semver.Parse(m[2])
+	if err != nil {
+		return version{}, false, fmt.Errorf("%v on line %v", err, i)
+	}
+	if s := semver.ParseStyle(m[2]); s != nil {
+		v.style = *s
+	}
+	v.sep = m[3]
+	v.date = m[4]
+	v.anchorSep = m[5]
+	v.anchor = m[6]
+	return v, true, nil
+}
+
+// parseUnderline returns true if the line with the 0-based index i is a setext
+// underline for the last parsed version heading. If it is, the underline is
+// recorded in the version.
+func (c *Content) parseUnderline(i int, line string) bool {
+	n := len(c.versions)
+	if n == 0 || c.versions[n-1].line != i || !underlineRE.MatchString(line) {
+		return false
+	}
+	c.versions[n-1].underline = line[0]
+	return true
+}
+
+// ReadTop parses the top n versions of the CHANGES file read from r. Reading
+// stops at the heading of the version that follows these, so only the lines
+// of the top n versions are held in memory. This makes ReadTop suitable for
+// extracting the most recent release notes from very large CHANGES files.
+// The returned Content is truncated, and should not be written back to the
+// CHANGES file.
+func ReadTop(r io.Reader, n int) (*Content, error) {
+	return readWhile(r, func(versions []version) bool { return len(versions) < n })
+}
+
+// ReadUntil parses the versions of the CHANGES file read from r, up to and
+// including the version v. Reading stops at the heading of the version that
+// follows v, so only the lines up to the end of the release notes for v are
+// held in memory. If v is not found, then the whole file is parsed.
+// The returned Content is truncated, and should not be written back to the
+// CHANGES file.
+func ReadUntil(r io.Reader, v semver.Version) (*Content, error) {
+	return readWhile(r, func(versions []version) bool {
+		return len(versions) == 0 || versions[len(versions)-1].Version != v
+	})
+}
+
+// readWhile parses the lines read from r, stopping at the next version heading
+// once more returns false.
+func readWhile(r io.Reader, more func(versions []version) bool) (*Content, error) {
+	c := Content{}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxLineLength)
+	for i := 0; s.Scan(); i++ {
+		line := s.Text()
+		if c.parseUnderline(i, line) {
+			c.lines = append(c.lines, line)
+			continue
+		}
+		v, ok, err := parseVersion(i, line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if !more(c.versions) {
+				break
+			}
+			c.versions = append(c.versions, v)
+		}
+		c.lines = append(c.lines, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read CHANGES: %w", err)
+	}
+	return &c, nil
+}
+
 func (c Content) String() string {
+	if c.crlf {
+		return strings.Join(c.lines, "\r\n")
+	}
 	return strings.Join(c.lines, "\n")
 }
 
@@ -90,7 +204,7 @@ loop:
 			endLine = ver.line - 1
 			break loop
 		case ver.Version == v:
-			startLine = ver.line
+			startLine = ver.notesStart()
 		}
 	}
 	if startLine == -1 {
@@ -114,9 +228,63 @@ func (c version) String() string {
 	b.WriteString(c.style.Format(c.Version))
 	b.WriteString(c.sep)
 	b.WriteString(c.date)
+	if c.anchor != "" {
+		if c.anchorSep == "" && (c.date != "" || c.sep == "") {
+			b.WriteString(" ")
+		} else {
+			b.WriteString(c.anchorSep)
+		}
+		b.WriteString(c.anchor)
+	}
 	return b.String()
 }
 
+// underlineFor returns the setext underline for the heading line.
+func (c version) underlineFor(heading string) string {
+	return strings.Repeat(string([]byte{c.underline}), max(utf8.RuneCountInString(heading), 3))
+}
+
+// notesStart returns the 0-based line index of the first line following the
+// version heading (and its underline, if any).
+func (c version) notesStart() int {
+	if c.underline != 0 {
+		return c.line + 1
+	}
+	return c.line
+}
+
+// id returns the anchor identifier of the version heading. This is either the
+// custom anchor, or the identifier generated by GitHub from the heading text.
+func (c version) id() string {
+	if c.anchor != "" {
+		return c.anchor[2 : len(c.anchor)-1]
+	}
+	heading := strings.TrimLeft(c.String(), "# ")
+	b := strings.Builder{}
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// withVersion returns a copy of the version heading, with the semantic version
+// replaced with v. Any custom anchor that contains the old version is updated
+// to reference v.
+func (c version) withVersion(v semver.Version) version {
+	out := c
+	out.Version = v
+	if c.anchor != "" {
+		out.anchor = strings.Replace(c.anchor, c.style.Format(c.Version), c.style.Format(v), 1)
+		out.anchor = strings.Replace(out.anchor, c.Version.String(), v.String(), 1)
+	}
+	return out
+}
+
 // Versions returns all the versions found in the changes content in order
 // declared.
 func (c *Content) Versions() semver.List {
@@ -139,13 +307,11 @@ func (c *Content) CurrentVersion() semver.Version {
 // CurrentVersionNotes returns the release notes for the top most version.
 func (c *Content) CurrentVersionNotes() string {
 	if len(c.versions) > 0 {
-		from, to := c.versions[0].line+1, len(c.lines)
+		from, to := c.versions[0].notesStart(), len(c.lines)
 		if len(c.versions) > 1 {
-			to = c.versions[1].line
+			to = c.versions[1].line - 1
 		}
 		if from < to {
-			from-- // 0-based index
-			to--   // 0-based index
 			return strings.Join(c.lines[from:to], "\n")
 		}
 	}
@@ -153,18 +319,66 @@ func (c *Content) CurrentVersionNotes() string {
 	return ""
 }
 
+// CurrentVersionHasBreakingChanges returns true if the release notes for the
+// top most version document a breaking change, using either the 'BREAKING:',
+// 'BREAKING CHANGE:', 'BREAKING CHANGES:' or '⚠' markers at the start of a
+// line or list item. Notes generated by ConventionalNotes use the
+// 'BREAKING CHANGES:' heading for breaking changes.
+func (c *Content) CurrentVersionHasBreakingChanges() bool {
+	return breakingChangeRE.MatchString(c.CurrentVersionNotes())
+}
+
+// SetCurrentVersionNotes replaces the release notes for the top most version
+// with notes.
+func (c *Content) SetCurrentVersionNotes(notes string) error {
+	if len(c.versions) == 0 {
+		return fmt.Errorf("CHANGES file does not contain any versions")
+	}
+	from, to := c.versions[0].notesStart(), len(c.lines)
+	if len(c.versions) > 1 {
+		to = c.versions[1].line - 1
+	}
+	lines := append([]string{}, c.lines[:from]...)
+	lines = append(lines, "")
+	if notes != "" {
+		lines = append(lines, strings.Split(notes, "\n")...)
+		lines = append(lines, "")
+	}
+	lines = append(lines, c.lines[to:]...)
+	c.lines = lines
+	c.versions = nil
+	return c.parse()
+}
+
 // AdjustCurrentVersion changes the semantic version for the top most version.
+// The heading level, setext underline and custom anchor of the heading are
+// preserved, and intra-document links to the heading are updated.
 func (c *Content) AdjustCurrentVersion(v semver.Version, t time.Time) bool {
 	if len(c.versions) == 0 {
 		return false
 	}
 	cv := &c.versions[0]
-	cv.Version = v
+	oldID := cv.id()
+	*cv = cv.withVersion(v)
 	cv.date = t.Format("2006-01-02")
 	if cv.sep == "" {
 		cv.sep = "  "
 	}
-	c.lines[cv.line-1] = cv.String()
+	heading := cv.String()
+	c.lines[cv.line-1] = heading
+	if cv.underline != 0 {
+		c.lines[cv.line] = cv.underlineFor(heading)
+	}
+	if newID := cv.id(); newID != oldID {
+		for i, line := range c.lines {
+			c.lines[i] = linkRE.ReplaceAllStringFunc(line, func(link string) string {
+				if linkRE.FindStringSubmatch(link)[1] == oldID {
+					return "](#" + newID + ")"
+				}
+				return link
+			})
+		}
+	}
 	return true
 }
 
@@ -186,14 +400,28 @@ func (c *Content) AddNewVersion(v semver.Version, t time.Time, content string) e
 		existing := c.versions[0]
 		h.prefix = existing.prefix
 		h.style = existing.style
-		h.sep = existing.sep
+		if h.date != "" {
+			h.sep = existing.sep
+		}
+		h.underline = existing.underline
+		// Only adopt the custom anchor if it is derived from the version, as
+		// anchors must be unique.
+		if a := existing.withVersion(v).anchor; a != existing.anchor {
+			h.anchorSep = existing.anchorSep
+			h.anchor = a
+		}
 	}
 
 	lines := append([]string{}, c.lines[0:at]...)
 	if len(lines) == 0 || lines[len(lines)-1] != "" {
 		lines = append(lines, "")
 	}
-	lines = append(lines, h.String(), "")
+	heading := h.String()
+	lines = append(lines, heading)
+	if h.underline != 0 {
+		lines = append(lines, h.underlineFor(heading))
+	}
+	lines = append(lines, "")
 	if content != "" {
 		lines = append(lines, strings.Split(content, "\n")...)
 		lines = append(lines, "")
@@ -207,6 +435,14 @@ func (c *Content) AddNewVersion(v semver.Version, t time.Time, content string) e
 // Validate checks the CHANGES content is well formed, returning any errors
 // found.
 func (c *Content) Validate(isDevelopmentBranch bool) []error {
+	return c.ValidateWithFlavorOrder(isDevelopmentBranch, nil)
+}
+
+// ValidateWithFlavorOrder checks the CHANGES content is well formed, like
+// Validate, additionally permitting older pre-release versions with flavors
+// listed in the policy order. If order is not nil, then versions are required
+// to be ordered using the policy, including their flavors.
+func (c *Content) ValidateWithFlavorOrder(isDevelopmentBranch bool, order semver.FlavorOrder) []error {
 	if len(c.versions) == 0 {
 		return []error{fmt.Errorf("CHANGES file does not contain any versions")}
 	}
@@ -222,11 +458,16 @@ func (c *Content) Validate(isDevelopmentBranch bool) []error {
 
 	for i, curr := range c.versions[1:] {
 		next := c.versions[i]
-		if curr.Flavor != "" {
+		if curr.Flavor != "" && (order == nil || !order.Contains(curr.Flavor)) {
 			errs = append(errs, fmt.Errorf("Version %v on line %v is flavored. Only the current version can be flavored",
 				curr.Version, curr.line))
 		}
-		if !next.GreaterThan(curr.Version, false) {
+		if order != nil {
+			if order.Compare(next.Version, curr.Version) <= 0 {
+				errs = append(errs, fmt.Errorf("Version %v on line %v is not greater than version %v on line %v (flavor order: %v)",
+					next.Version, next.line, curr.Version, curr.line, order))
+			}
+		} else if !next.GreaterThan(curr.Version, false) {
 			errs = append(errs, fmt.Errorf("Version %v on line %v is not greater than version %v on line %v",
 				next.Version, next.line, curr.Version, curr.line))
 		}
@@ -234,3 +475,38 @@ func (c *Content) Validate(isDevelopmentBranch bool) []error {
 
 	return errs
 }
+
+// ValidateBump checks that the release version v for the top most version is
+// consistent with the breaking changes documented in its release notes,
+// returning any errors found.
+// If breaking changes are documented, then v must bump the major version of
+// the previous release (or the minor version for 0.x.x releases). If the major
+// version is bumped, then breaking changes are expected to be documented.
+func (c *Content) ValidateBump(v semver.Version) []error {
+	errs := []error{}
+	if len(c.versions) < 2 {
+		return errs // No previous release to compare against.
+	}
+	prev := c.versions[1].Version
+	breaking := c.CurrentVersionHasBreakingChanges()
+	majorBump := v.Major > prev.Major
+	if prev.Major == 0 && v.Major == 0 {
+		majorBump = v.Minor > prev.Minor
+	}
+	switch {
+	case breaking && !majorBump:
+		errs = append(errs, fmt.Errorf("Release notes for %v document breaking changes, but version %v is not a major version bump from %v",
+			c.versions[0].Version, v, prev))
+	case !breaking && v.Major > prev.Major:
+		errs = append(errs, fmt.Errorf("Version %v is a major version bump from %v, but the release notes do not document any breaking changes",
+			v, prev))
+	}
+	return errs
+}
+
+func max(x, y int) int {
+	if x < y {
+		return y
+	}
+	return x
+}
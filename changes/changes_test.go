@@ -17,6 +17,7 @@ package changes_test
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -135,6 +136,52 @@ func TestString(t *testing.T) {
 	check(t, "String()", c.String(), devNotes)
 }
 
+func TestReadCRLF(t *testing.T) {
+	c, err := changes.Read(strings.ReplaceAll(devNotes, "\n", "\r\n"))
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	check(t, "CurrentVersionNotes()", c.CurrentVersionNotes(), `xxx
+Notes about the 2.2.1 patch release
+yyy`)
+	ver := semver.Version{Major: 2, Minor: 2, Patch: 1}
+	date, _ := time.Parse("2006-01-02", "2019-07-10")
+	c.AdjustCurrentVersion(ver, date)
+	if err := c.AddNewVersion(semver.Version{Major: 2, Minor: 2, Patch: 2, Flavor: "dev"}, time.Time{}, "bark"); err != nil {
+		t.Errorf("AddNewVersion() returned error: %v", err)
+	}
+	got := c.String()
+	if strings.Contains(strings.ReplaceAll(got, "\r\n", ""), "\n") {
+		t.Errorf("String() contains LF line endings:\n%q", got)
+	}
+	check(t, "String()", strings.ReplaceAll(got, "\r\n", "\n"), `
+### 2.2.2-dev
+
+bark
+
+### 2.2.1  2019-07-10
+xxx
+Notes about the 2.2.1 patch release
+yyy
+### 2.2.0    2020-02-10
+
+Notes about the 2.2.0 minor release
+
+### 2.1.0
+
+Notes about the 2.1.0 minor release
+
+### 2.0.0    2020-01-01
+
+Notes about the 2.0.0 major release
+
+### 1.0.0
+
+Notes about the 1.0.0 major release
+`)
+}
+
 func TestCurrentVersion(t *testing.T) {
 	c, err := changes.Read(devNotes)
 	if err != nil {
@@ -301,6 +348,31 @@ func TestValidateVersionOrder(t *testing.T) {
 	})
 }
 
+func TestValidateWithFlavorOrder(t *testing.T) {
+	c, err := changes.Read(`
+### 2.0.0-dev
+
+### 2.0.0-rc2
+
+### 2.0.0-rc10
+
+### 2.0.0-beta1
+
+### 1.0.0-nightly
+
+### 1.0.0
+`)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	order := semver.FlavorOrder{"beta", "rc", "", "dev"}
+	check(t, "ValidateWithFlavorOrder()", c.ValidateWithFlavorOrder(true, order), []error{
+		fmt.Errorf(`Version 2.0.0-rc2 on line 4 is not greater than version 2.0.0-rc10 on line 6 (flavor order: beta < rc < "" < dev)`),
+		fmt.Errorf("Version 1.0.0-nightly on line 10 is flavored. Only the current version can be flavored"),
+	})
+}
+
 func TestReleaseNotes(t *testing.T) {
 	c, err := changes.Read(devNotes)
 	if err != nil {
@@ -327,3 +399,577 @@ yyy`},
 		check(t, fmt.Sprintf("%v notes", test.v), notes, test.notes)
 	}
 }
+
+func TestWriteHTML(t *testing.T) {
+	c, err := changes.Read(`# Release notes for <fooglezap>
+
+## 1.1.0-dev
+
+* Added the ` + "`woof`" + ` command.
+* Fixed a bug.
+
+## 1.0.0     2020-01-01
+
+First release.
+`)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	b := strings.Builder{}
+	if err := c.WriteHTML(&b, "fooglezap"); err != nil {
+		t.Errorf("WriteHTML() returned error: %v", err)
+		return
+	}
+	got := b.String()
+	for _, expect := range []string{
+		`<title>fooglezap</title>`,
+		`<h1>Release notes for &lt;fooglezap&gt;</h1>`,
+		`<div class="version" id="v1.1.0-dev">`,
+		`<li>Added the <code>woof</code> command.</li>`,
+		`<div class="version" id="v1.0.0">`,
+		`<div class="date">2020-01-01</div>`,
+		`<p>First release.</p>`,
+	} {
+		if !strings.Contains(got, expect) {
+			t.Errorf("WriteHTML() output does not contain '%v'. Output:\n%v", expect, got)
+		}
+	}
+}
+
+func TestWriteVersionHTML(t *testing.T) {
+	c, err := changes.Read(devNotes)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	b := strings.Builder{}
+	if err := c.WriteVersionHTML(&b, "notes", "index.html", semver.Version{Major: 2, Minor: 1}); err != nil {
+		t.Errorf("WriteVersionHTML() returned error: %v", err)
+		return
+	}
+	got := b.String()
+	if !strings.Contains(got, `<p>Notes about the 2.1.0 minor release</p>`) {
+		t.Errorf("WriteVersionHTML() output does not contain the version notes. Output:\n%v", got)
+	}
+	if strings.Contains(got, `2.2.0`) {
+		t.Errorf("WriteVersionHTML() output contains other versions. Output:\n%v", got)
+	}
+	if err := c.WriteVersionHTML(&b, "notes", "", semver.Version{Major: 9}); err == nil {
+		t.Errorf("WriteVersionHTML() did not return error for unknown version")
+	}
+}
+
+func TestAnchorAndPageName(t *testing.T) {
+	for _, test := range []struct {
+		v      semver.Version
+		anchor string
+	}{
+		{semver.Version{Major: 1, Minor: 2, Patch: 3}, "v1.2.3"},
+		{semver.Version{Major: 1, Minor: 2, Flavor: "rc1"}, "v1.2.0-rc1"},
+		{semver.Version{Major: 1, Minor: 2, Patch: 3, Metadata: "build.5"}, "v1.2.3--build.5"},
+		{semver.Version{Major: 1, Minor: 2, Flavor: "rc1", Metadata: "a-b"}, "v1.2.0-rc1--a-b"},
+		{semver.Version{Epoch: 2, Major: 1, Minor: 2, Patch: 3}, "v2_1.2.3"},
+		{semver.Version{Epoch: 1, Major: 1, Minor: 2, Metadata: "ci"}, "v1_1.2.0--ci"},
+	} {
+		check(t, fmt.Sprintf("Anchor(%v)", test.v), changes.Anchor(test.v), test.anchor)
+		check(t, fmt.Sprintf("PageName(%v)", test.v), changes.PageName(test.v), test.anchor+".html")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a, err := changes.Read(devNotes)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	b, err := changes.Read(`
+### 2.2.0    2020-02-10
+
+Notes about the 2.2.0 minor release
+
+### 2.1.0
+
+Different notes about the 2.1.0 minor release
+
+### 2.0.1
+
+Notes about the 2.0.1 patch release
+
+### 2.0.0    2020-01-01
+
+Notes about the 2.0.0 major release
+`)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	check(t, "Compare()", changes.Compare(a, b), changes.Diff{
+		OnlyInA: semver.List{
+			{Major: 2, Minor: 2, Patch: 1, Flavor: "dev"},
+			{Major: 1, Minor: 0, Patch: 0},
+		},
+		OnlyInB: semver.List{
+			{Major: 2, Minor: 0, Patch: 1},
+		},
+		NotesDiffer: semver.List{
+			{Major: 2, Minor: 1, Patch: 0},
+		},
+	})
+	if !changes.Compare(a, a).Empty() {
+		t.Errorf("Compare() of the same content was not empty")
+	}
+}
+
+func TestSetCurrentVersionNotes(t *testing.T) {
+	c, err := changes.Read(`# Release notes for fooglezap
+
+## 1.3.0-dev
+
+[Add release notes here]
+
+## 1.2.3     2015-11-30
+
+purr purr purr
+`)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	if err := c.SetCurrentVersionNotes("bark bark\nbark"); err != nil {
+		t.Errorf("SetCurrentVersionNotes() returned error: %v", err)
+	}
+	check(t, "String()", c.String(), `# Release notes for fooglezap
+
+## 1.3.0-dev
+
+bark bark
+bark
+
+## 1.2.3     2015-11-30
+
+purr purr purr
+`)
+	check(t, "CurrentVersionNotes()", c.CurrentVersionNotes(), `
+bark bark
+bark
+`)
+}
+
+func TestConventionalNotes(t *testing.T) {
+	got := changes.ConventionalNotes([]string{
+		"feat: add the woof command",
+		"fix(parser): handle empty files",
+		"chore: update dependencies",
+		"feat(api)!: remove the bark function",
+		"Update README",
+		"docs: describe the woof command",
+		"fix: do not crash on startup",
+		"refactor(cli): rename the --loud flag\n\nBREAKING CHANGE: --loud is now --volume=max",
+		"feat: add the growl command\n\nRefs: #42\nBREAKING-CHANGE: growl replaces bark",
+		"fix: handle barking\n\nNot a BREAKING CHANGE: the output is unchanged",
+	})
+	check(t, "ConventionalNotes()", got, `BREAKING CHANGES:
+
+* api: remove the bark function
+* cli: rename the --loud flag
+* add the growl command
+
+Features:
+
+* add the woof command
+
+Bug Fixes:
+
+* parser: handle empty files
+* do not crash on startup
+* handle barking
+
+Documentation:
+
+* describe the woof command
+
+Other Changes:
+
+* Update README`)
+}
+
+func TestConventionalNotesHaveBreakingChanges(t *testing.T) {
+	for _, test := range []struct {
+		messages []string
+		expect   bool
+	}{
+		{[]string{"feat: add the woof command", "fix: do not crash"}, false},
+		{[]string{"feat: add the woof command", "feat(api)!: remove the bark function"}, true},
+		{[]string{"refactor: rename bark\n\nBREAKING CHANGE: bark is now woof"}, true},
+	} {
+		c, err := changes.Read(`
+## 2.3.0-dev
+
+## 2.2.0
+`)
+		if err != nil {
+			t.Errorf("changes.Read() returned error: %v", err)
+			return
+		}
+		notes := changes.ConventionalNotes(test.messages)
+		if err := c.SetCurrentVersionNotes(notes); err != nil {
+			t.Errorf("SetCurrentVersionNotes() returned error: %v", err)
+			continue
+		}
+		check(t, fmt.Sprintf("CurrentVersionHasBreakingChanges() for %q", test.messages),
+			c.CurrentVersionHasBreakingChanges(), test.expect)
+	}
+}
+
+func TestValidateBump(t *testing.T) {
+	const breaking = `
+### 2.2.1-dev
+
+* BREAKING: Removed the woof function
+
+### 2.2.0
+
+Notes about the 2.2.0 minor release
+`
+	const nonBreaking = `
+### 2.2.1-dev
+
+* Added the woof function
+
+### 2.2.0
+
+Notes about the 2.2.0 minor release
+`
+	const notBreaking = `
+### 2.2.1-dev
+
+* Added the NON-BREAKING woof option
+* The bark function is not BREAKING: it keeps its signature
+
+### 2.2.0
+
+Notes about the 2.2.0 minor release
+`
+	const breakingChange = `
+### 2.2.1-dev
+
+Fixed the woof function.
+
+BREAKING CHANGE: woof now returns an error
+
+### 2.2.0
+
+Notes about the 2.2.0 minor release
+`
+	const initialDevelopment = `
+### 0.3.0-dev
+
+* ⚠ Removed the woof function
+
+### 0.2.0
+
+Notes about the 0.2.0 minor release
+`
+	for _, test := range []struct {
+		changes string
+		v       semver.Version
+		expect  []error
+	}{
+		{breaking, semver.Version{Major: 3}, []error{}},
+		{breaking, semver.Version{Major: 2, Minor: 3}, []error{
+			fmt.Errorf("Release notes for 2.2.1-dev document breaking changes, but version 2.3.0 is not a major version bump from 2.2.0"),
+		}},
+		{nonBreaking, semver.Version{Major: 2, Minor: 2, Patch: 1}, []error{}},
+		{nonBreaking, semver.Version{Major: 3}, []error{
+			fmt.Errorf("Version 3.0.0 is a major version bump from 2.2.0, but the release notes do not document any breaking changes"),
+		}},
+		{notBreaking, semver.Version{Major: 2, Minor: 2, Patch: 1}, []error{}},
+		{breakingChange, semver.Version{Major: 2, Minor: 2, Patch: 1}, []error{
+			fmt.Errorf("Release notes for 2.2.1-dev document breaking changes, but version 2.2.1 is not a major version bump from 2.2.0"),
+		}},
+		{initialDevelopment, semver.Version{Major: 0, Minor: 3}, []error{}},
+		{initialDevelopment, semver.Version{Major: 0, Minor: 2, Patch: 1}, []error{
+			fmt.Errorf("Release notes for 0.3.0-dev document breaking changes, but version 0.2.1 is not a major version bump from 0.2.0"),
+		}},
+	} {
+		c, err := changes.Read(test.changes)
+		if err != nil {
+			t.Errorf("changes.Read() returned error: %v", err)
+			return
+		}
+		check(t, fmt.Sprintf("ValidateBump(%v)", test.v), c.ValidateBump(test.v), test.expect)
+	}
+}
+
+func TestReadTop(t *testing.T) {
+	for _, test := range []struct {
+		n      int
+		expect semver.List
+	}{
+		{0, semver.List{}},
+		{1, semver.List{{Major: 2, Minor: 2, Patch: 1, Flavor: "dev"}}},
+		{2, semver.List{{Major: 2, Minor: 2, Patch: 1, Flavor: "dev"}, {Major: 2, Minor: 2}}},
+		{10, semver.List{
+			{Major: 2, Minor: 2, Patch: 1, Flavor: "dev"},
+			{Major: 2, Minor: 2, Patch: 0},
+			{Major: 2, Minor: 1, Patch: 0},
+			{Major: 2, Minor: 0, Patch: 0},
+			{Major: 1, Minor: 0, Patch: 0},
+		}},
+	} {
+		c, err := changes.ReadTop(strings.NewReader(devNotes), test.n)
+		if err != nil {
+			t.Errorf("changes.ReadTop() returned error: %v", err)
+			return
+		}
+		check(t, fmt.Sprintf("ReadTop(%v).Versions()", test.n), c.Versions(), test.expect)
+	}
+
+	c, err := changes.ReadTop(strings.NewReader(devNotes), 2)
+	if err != nil {
+		t.Errorf("changes.ReadTop() returned error: %v", err)
+		return
+	}
+	notes, _ := c.ReleaseNotes(semver.Version{Major: 2, Minor: 2})
+	check(t, "ReadTop(2).ReleaseNotes(2.2.0)", notes, `Notes about the 2.2.0 minor release`)
+}
+
+func TestReadUntil(t *testing.T) {
+	v := semver.Version{Major: 2, Minor: 1}
+	c, err := changes.ReadUntil(strings.NewReader(devNotes), v)
+	if err != nil {
+		t.Errorf("changes.ReadUntil() returned error: %v", err)
+		return
+	}
+	check(t, "ReadUntil().Versions()", c.Versions(), semver.List{
+		{Major: 2, Minor: 2, Patch: 1, Flavor: "dev"},
+		{Major: 2, Minor: 2, Patch: 0},
+		{Major: 2, Minor: 1, Patch: 0},
+	})
+	notes, _ := c.ReleaseNotes(v)
+	check(t, "ReadUntil().ReleaseNotes()", notes, `Notes about the 2.1.0 minor release`)
+}
+
+func TestHeadingPreservation(t *testing.T) {
+	date, _ := time.Parse("2006-01-02", "2019-07-10")
+	for _, test := range []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{"##", `
+## 1.1.0-dev
+
+Notes about the 1.1.0 release
+
+## 1.0.0
+
+Notes about the 1.0.0 release
+`, `
+## 1.1.1-dev
+
+[Add release notes here]
+
+## 1.1.0  2019-07-10
+
+Notes about the 1.1.0 release
+
+## 1.0.0
+
+Notes about the 1.0.0 release
+`}, {"###", `
+### 1.1.0-dev
+
+Notes about the 1.1.0 release
+`, `
+### 1.1.1-dev
+
+[Add release notes here]
+
+### 1.1.0  2019-07-10
+
+Notes about the 1.1.0 release
+`}, {"setext", `
+1.1.0-dev
+=========
+
+Notes about the 1.1.0 release
+
+1.0.0 2018-01-01
+----------------
+
+Notes about the 1.0.0 release
+`, `
+1.1.1-dev
+=========
+
+[Add release notes here]
+
+1.1.0  2019-07-10
+=================
+
+Notes about the 1.1.0 release
+
+1.0.0 2018-01-01
+----------------
+
+Notes about the 1.0.0 release
+`}, {"custom anchors", `
+See [the latest release](#v1.1.0-dev) and [the first release](#first).
+
+## 1.1.0-dev {#v1.1.0-dev}
+
+Notes about the 1.1.0 release
+
+## 1.0.0 {#first}
+
+Notes about the 1.0.0 release
+`, `
+See [the latest release](#v1.1.0) and [the first release](#first).
+
+## 1.1.1-dev {#v1.1.1-dev}
+
+[Add release notes here]
+
+## 1.1.0 2019-07-10 {#v1.1.0}
+
+Notes about the 1.1.0 release
+
+## 1.0.0 {#first}
+
+Notes about the 1.0.0 release
+`}, {"generated anchors", `
+See [the latest release](#110-dev).
+
+## 1.1.0-dev
+
+Notes about the 1.1.0 release
+`, `
+See [the latest release](#110--2019-07-10).
+
+## 1.1.1-dev
+
+[Add release notes here]
+
+## 1.1.0  2019-07-10
+
+Notes about the 1.1.0 release
+`},
+	} {
+		c, err := changes.Read(test.input)
+		if err != nil {
+			t.Errorf("%v: changes.Read() returned error: %v", test.name, err)
+			continue
+		}
+		c.AdjustCurrentVersion(semver.Version{Major: 1, Minor: 1}, date)
+		if err := c.AddNewVersion(semver.Version{Major: 1, Minor: 1, Patch: 1, Flavor: "dev"}, time.Time{}, "[Add release notes here]"); err != nil {
+			t.Errorf("%v: AddNewVersion() returned error: %v", test.name, err)
+			continue
+		}
+		check(t, test.name+" String()", c.String(), test.expect)
+		notes, _ := c.ReleaseNotes(semver.Version{Major: 1, Minor: 1})
+		check(t, test.name+" ReleaseNotes()", notes, "Notes about the 1.1.0 release")
+	}
+}
+
+func TestReadBuildMetadata(t *testing.T) {
+	c, err := changes.Read(`
+## 1.1.0+build.2
+
+## 1.0.0+build.1
+`)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	check(t, "Versions()", c.Versions(), semver.List{
+		{Major: 1, Minor: 1, Metadata: "build.2"},
+		{Major: 1, Minor: 0, Metadata: "build.1"},
+	})
+}
+
+func TestReadFourComponentVersions(t *testing.T) {
+	c, err := changes.Read(`
+## 1.2.0.1
+
+## 1.2.0.0
+`)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	check(t, "Versions()", c.Versions(), semver.List{
+		{Major: 1, Minor: 2, Build: 1},
+		{Major: 1, Minor: 2},
+	})
+}
+
+func TestFlavorSeparators(t *testing.T) {
+	c, err := changes.Read(`
+## 1.2.0rc2
+
+Notes for rc2.
+
+## 1.2.0_rc1
+
+## 1.1.0
+`)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	check(t, "Versions()", c.Versions(), semver.List{
+		{Major: 1, Minor: 2, Flavor: "rc2"},
+		{Major: 1, Minor: 2, Flavor: "rc1"},
+		{Major: 1, Minor: 1},
+	})
+	date := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	c.AdjustCurrentVersion(semver.Version{Major: 1, Minor: 2, Flavor: "rc3"}, date)
+	check(t, "String()", c.String(), `
+## 1.2.0rc3  2020-06-01
+
+Notes for rc2.
+
+## 1.2.0_rc1
+
+## 1.1.0
+`)
+}
+
+func TestReadWildcardIsNotAVersion(t *testing.T) {
+	c, err := changes.Read(`
+## 1.3.0-dev
+
+Backports to 1.2.x are listed below.
+
+1.2.x
+
+## 1.2.0
+`)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	check(t, "Versions()", c.Versions(), semver.List{
+		{Major: 1, Minor: 3, Flavor: "dev"},
+		{Major: 1, Minor: 2},
+	})
+}
+
+func TestReadEpochVersions(t *testing.T) {
+	c, err := changes.Read(`
+## 1:1.0.0
+
+## 5.2.0
+`)
+	if err != nil {
+		t.Errorf("changes.Read() returned error: %v", err)
+		return
+	}
+	check(t, "Versions()", c.Versions(), semver.List{
+		{Epoch: 1, Major: 1},
+		{Major: 5, Minor: 2},
+	})
+	check(t, "Validate()", c.Validate(false), []error{})
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changes
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// conventionalRE is the regular expression used to parse conventional
+	// commit subjects (https://www.conventionalcommits.org).
+	conventionalRE = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?: *(.+)$`)
+
+	// breakingFooterRE matches the footer used to document a breaking change in
+	// the body of a conventional commit message.
+	breakingFooterRE = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+
+	// conventionalGroups lists the headings used for each of the conventional
+	// commit types, in the order they are written. Types not listed here (chore,
+	// ci, test, etc) are omitted from the generated notes.
+	conventionalGroups = []struct {
+		types   []string
+		heading string
+	}{
+		{[]string{"feat", "feature"}, "Features"},
+		{[]string{"fix", "bugfix"}, "Bug Fixes"},
+		{[]string{"perf"}, "Performance Improvements"},
+		{[]string{"revert"}, "Reverts"},
+		{[]string{"docs"}, "Documentation"},
+		{[]string{"refactor"}, "Code Refactoring"},
+	}
+)
+
+// ConventionalNotes returns release notes generated from the given commit
+// messages, grouped by conventional commit type. Each message is a subject
+// line, optionally followed by a body. Breaking changes (marked with a '!'
+// after the type, or a 'BREAKING CHANGE:' footer in the body) are listed
+// first, under a 'BREAKING CHANGES:' heading that is recognized by
+// CurrentVersionHasBreakingChanges. Subjects that do not follow the conventional commit format are listed
+// under 'Other Changes'.
+func ConventionalNotes(messages []string) string {
+	breaking, other := []string{}, []string{}
+	groups := make([][]string, len(conventionalGroups))
+
+nextSubject:
+	for _, msg := range messages {
+		msg = strings.TrimSpace(msg)
+		if msg == "" {
+			continue
+		}
+		s, body := msg, ""
+		if i := strings.Index(msg, "\n"); i >= 0 {
+			s, body = strings.TrimSpace(msg[:i]), msg[i+1:]
+		}
+		m := conventionalRE.FindStringSubmatch(s)
+		if len(m) == 0 {
+			other = append(other, s)
+			continue
+		}
+		typ, scope, bang, desc := strings.ToLower(m[1]), m[2], m[3], m[4]
+		entry := desc
+		if scope != "" {
+			entry = scope + ": " + desc
+		}
+		if bang != "" || breakingFooterRE.MatchString(body) {
+			breaking = append(breaking, entry)
+			continue
+		}
+		for i, g := range conventionalGroups {
+			for _, t := range g.types {
+				if t == typ {
+					groups[i] = append(groups[i], entry)
+					continue nextSubject
+				}
+			}
+		}
+	}
+
+	sections := []string{}
+	add := func(heading string, entries []string) {
+		if len(entries) == 0 {
+			return
+		}
+		b := strings.Builder{}
+		b.WriteString(heading)
+		b.WriteString(":\n")
+		for _, e := range entries {
+			b.WriteString("\n* ")
+			b.WriteString(e)
+		}
+		sections = append(sections, b.String())
+	}
+	add("BREAKING CHANGES", breaking)
+	for i, g := range conventionalGroups {
+		add(g.heading, groups[i])
+	}
+	add("Other Changes", other)
+	return strings.Join(sections, "\n\n")
+}
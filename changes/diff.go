@@ -0,0 +1,58 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changes
+
+import (
+	"github.com/ben-clayton/release-me/semver"
+)
+
+// Diff holds the differences between two CHANGES contents, A and B.
+type Diff struct {
+	OnlyInA     semver.List // Versions found in A, but not in B
+	OnlyInB     semver.List // Versions found in B, but not in A
+	NotesDiffer semver.List // Versions found in both A and B, with different release notes
+}
+
+// Compare returns the differences between the CHANGES contents a and b.
+func Compare(a, b *Content) Diff {
+	d := Diff{
+		OnlyInA:     semver.List{},
+		OnlyInB:     semver.List{},
+		NotesDiffer: semver.List{},
+	}
+	inA, inB := a.Versions().Set(), b.Versions().Set()
+	for _, v := range a.Versions() {
+		if !inB.Contains(v) {
+			d.OnlyInA = append(d.OnlyInA, v)
+			continue
+		}
+		notesA, _ := a.ReleaseNotes(v)
+		notesB, _ := b.ReleaseNotes(v)
+		if notesA != notesB {
+			d.NotesDiffer = append(d.NotesDiffer, v)
+		}
+	}
+	for _, v := range b.Versions() {
+		if !inA.Contains(v) {
+			d.OnlyInB = append(d.OnlyInB, v)
+		}
+	}
+	return d
+}
+
+// Empty returns true if the diff holds no differences.
+func (d Diff) Empty() bool {
+	return len(d.OnlyInA) == 0 && len(d.OnlyInB) == 0 && len(d.NotesDiffer) == 0
+}
@@ -0,0 +1,224 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changes
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/ben-clayton/release-me/semver"
+)
+
+// anchorReplacer replaces the characters of a version that are not safe to use
+// in file names and URLs: the ':' of an epoch, and the '+' before build
+// metadata. '--' cannot otherwise appear before the build metadata, so the
+// replacements are unambiguous.
+var anchorReplacer = strings.NewReplacer(":", "_", "+", "--")
+
+// Anchor returns the HTML anchor used for the version v.
+func Anchor(v semver.Version) string {
+	return "v" + anchorReplacer.Replace(v.String())
+}
+
+// PageName returns the file name used for the per-version HTML page for v.
+func PageName(v semver.Version) string {
+	return Anchor(v) + ".html"
+}
+
+// htmlVersion is a single version section passed to the HTML templates.
+type htmlVersion struct {
+	Anchor string
+	Title  string
+	Date   string
+	Notes  template.HTML
+}
+
+// htmlPage is the data passed to the HTML templates.
+type htmlPage struct {
+	Title    string
+	Index    string
+	Preamble template.HTML
+	Versions []htmlVersion
+}
+
+// WriteHTML writes the CHANGES content to w as a single styled HTML page, with
+// an anchor for each version.
+func (c *Content) WriteHTML(w io.Writer, title string) error {
+	page := htmlPage{Title: title}
+	if len(c.versions) > 0 {
+		page.Preamble = markdownToHTML(c.lines[:c.versions[0].line-1])
+	} else {
+		page.Preamble = markdownToHTML(c.lines)
+	}
+	for i := range c.versions {
+		page.Versions = append(page.Versions, c.htmlVersion(i))
+	}
+	return htmlTemplate.Execute(w, page)
+}
+
+// WriteVersionHTML writes a styled HTML page holding only the release notes
+// for the version v to w. index is the relative URL of the page holding all
+// versions, and may be empty.
+func (c *Content) WriteVersionHTML(w io.Writer, title, index string, v semver.Version) error {
+	for i, ver := range c.versions {
+		if ver.Version == v {
+			return htmlTemplate.Execute(w, htmlPage{
+				Title:    fmt.Sprintf("%v %v", title, v),
+				Index:    index,
+				Versions: []htmlVersion{c.htmlVersion(i)},
+			})
+		}
+	}
+	return fmt.Errorf("Version %v not found", v)
+}
+
+func (c *Content) htmlVersion(i int) htmlVersion {
+	ver := c.versions[i]
+	from, to := ver.notesStart(), len(c.lines)
+	if i+1 < len(c.versions) {
+		to = c.versions[i+1].line - 1
+	}
+	return htmlVersion{
+		Anchor: Anchor(ver.Version),
+		Title:  ver.style.Format(ver.Version),
+		Date:   ver.date,
+		Notes:  markdownToHTML(c.lines[from:to]),
+	}
+}
+
+// markdownToHTML performs a minimal conversion of the markdown lines to HTML.
+// Only headings, bullet lists, code blocks, inline code and paragraphs are
+// handled, which covers the content typically found in a CHANGES file.
+func markdownToHTML(lines []string) template.HTML {
+	b := strings.Builder{}
+	inList, inCode, inPara := false, false, false
+	closeBlocks := func() {
+		if inList {
+			b.WriteString("</ul>\n")
+			inList = false
+		}
+		if inPara {
+			b.WriteString("</p>\n")
+			inPara = false
+		}
+	}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				b.WriteString("</code></pre>\n")
+			} else {
+				closeBlocks()
+				b.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		switch {
+		case inCode:
+			b.WriteString(html.EscapeString(line))
+			b.WriteString("\n")
+		case trimmed == "":
+			closeBlocks()
+		case strings.HasPrefix(trimmed, "#"):
+			closeBlocks()
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			if level > 6 {
+				level = 6
+			}
+			text := strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			fmt.Fprintf(&b, "<h%d>%v</h%d>\n", level, inlineToHTML(text), level)
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* "):
+			if inPara {
+				b.WriteString("</p>\n")
+				inPara = false
+			}
+			if !inList {
+				b.WriteString("<ul>\n")
+				inList = true
+			}
+			fmt.Fprintf(&b, "<li>%v</li>\n", inlineToHTML(trimmed[2:]))
+		case inList:
+			// Continuation of the previous list item.
+			b.WriteString(inlineToHTML(trimmed))
+			b.WriteString("\n")
+		default:
+			if !inPara {
+				b.WriteString("<p>")
+				inPara = true
+			} else {
+				b.WriteString("\n")
+			}
+			b.WriteString(inlineToHTML(trimmed))
+		}
+	}
+	if inCode {
+		b.WriteString("</code></pre>\n")
+	}
+	closeBlocks()
+	return template.HTML(b.String())
+}
+
+// inlineToHTML escapes s, converting `code` spans to <code> elements.
+func inlineToHTML(s string) string {
+	parts := strings.Split(s, "`")
+	if len(parts)%2 == 0 { // Unbalanced backticks. Leave as-is.
+		return html.EscapeString(s)
+	}
+	b := strings.Builder{}
+	for i, p := range parts {
+		if i%2 == 1 {
+			b.WriteString("<code>")
+			b.WriteString(html.EscapeString(p))
+			b.WriteString("</code>")
+		} else {
+			b.WriteString(html.EscapeString(p))
+		}
+	}
+	return b.String()
+}
+
+var htmlTemplate = template.Must(template.New("changes").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; color: #24292e; line-height: 1.5; }
+h1 { border-bottom: 1px solid #eaecef; }
+.version { margin-top: 2em; }
+.version h2 { border-bottom: 1px solid #eaecef; margin-bottom: 0; }
+.version h2 a { color: inherit; text-decoration: none; }
+.date { color: #6a737d; font-size: 0.9em; }
+code { background: #f6f8fa; padding: 0.2em 0.4em; border-radius: 3px; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; }
+pre code { padding: 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Index}}<p><a href="{{.Index}}">All versions</a></p>{{end}}
+{{.Preamble}}
+{{range .Versions}}<div class="version" id="{{.Anchor}}">
+<h2><a href="#{{.Anchor}}">{{.Title}}</a></h2>
+{{if .Date}}<div class="date">{{.Date}}</div>{{end}}
+{{.Notes}}
+</div>
+{{end}}</body>
+</html>
+`))
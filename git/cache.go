@@ -0,0 +1,102 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Cache maintains long-lived, bare clones of remotes, so that repeated
+// checkouts of the same remote only need to fetch the new changes.
+// Checkouts are git worktrees of the cached clone. A worktree can be deleted
+// with os.RemoveAll(), and will be pruned from the clone by the next Checkout.
+type Cache struct {
+	git  *Git
+	root string // Directory holding the cached clones
+}
+
+// NewCache returns a Cache that uses g, holding the clones in the directory
+// root.
+func NewCache(g *Git, root string) *Cache {
+	return &Cache{git: g, root: root}
+}
+
+// Checkout fetches the latest changes from url into the cached clone, and
+// checks out ref, a branch name or commit hash, into a new worktree at path.
+// path must not exist, or be an empty directory.
+// The go-git backend does not support worktrees, so Checkout performs a full
+// checkout of the branch ref instead.
+func (c *Cache) Checkout(ctx context.Context, url, ref, path string, cred Credentials) error {
+	if c.git.gogit != nil {
+		return c.git.CheckoutRemoteBranch(ctx, path, url, ref, CheckoutFlags{Credentials: cred})
+	}
+
+	dir, err := c.fetch(ctx, url, cred)
+	if err != nil {
+		return err
+	}
+
+	// Remove worktrees that have been deleted since the last checkout.
+	if _, err := c.git.run(ctx, dir, "worktree", "prune"); err != nil {
+		return err
+	}
+
+	rev := ref
+	if _, err := c.git.run(ctx, dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+ref); err == nil {
+		rev = "refs/heads/" + ref
+	}
+	if _, err := c.git.run(ctx, dir, "worktree", "add", "--detach", "--force", path, rev); err != nil {
+		return fmt.Errorf("Failed to checkout '%v': %w", ref, err)
+	}
+	return nil
+}
+
+// fetch fetches all the branches and tags of url into the cached clone,
+// creating the clone if it does not already exist. fetch returns the path to
+// the cached clone.
+func (c *Cache) fetch(ctx context.Context, url string, cred Credentials) (string, error) {
+	dir := filepath.Join(c.root, cacheName(url))
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return "", fmt.Errorf("mkdir '%v' failed: %w", dir, err)
+		}
+		if _, err := c.git.run(ctx, dir, "init", "--bare"); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+
+	args, env := cred.args(url)
+	args = append(args, "fetch", "--prune", url, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*")
+	if _, err := c.git.runEnv(ctx, dir, env, args...); err != nil {
+		return "", fmt.Errorf("Failed to fetch from '%v': %w", url, err)
+	}
+	return dir, nil
+}
+
+var cacheNameRE = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// cacheName returns the directory name of the cached clone for url.
+func cacheName(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	}
+	return strings.Trim(cacheNameRE.ReplaceAllString(url, "_"), "_")
+}
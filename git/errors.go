@@ -0,0 +1,135 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// Errors that may be returned by the Git operations. Use errors.Is() to test
+// for these.
+var (
+	ErrAuthFailed     = fmt.Errorf("Authentication with the git remote failed")
+	ErrNonFastForward = fmt.Errorf("The remote branch has changes that are not in the local branch")
+	ErrRefNotFound    = fmt.Errorf("The git reference was not found")
+	ErrMergeConflict  = fmt.Errorf("The changes could not be merged due to conflicts")
+	ErrNetwork        = fmt.Errorf("Communication with the git remote failed")
+)
+
+// Error is the error returned by a Git operation that failed for one of the
+// known reasons.
+type Error struct {
+	Kind error // One of ErrAuthFailed, ErrNonFastForward, ErrRefNotFound, ErrMergeConflict or ErrNetwork
+	Err  error // The underlying error
+}
+
+func (e Error) Error() string { return e.Kind.Error() + ": " + e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e Error) Unwrap() error { return e.Err }
+
+// Is returns true if target is the kind of the error.
+func (e Error) Is(target error) bool { return e.Kind == target }
+
+// stderrPatterns maps substrings of git's stderr output to the error kind.
+// Patterns are tested in order, so more specific patterns come first.
+var stderrPatterns = []struct {
+	substr string
+	kind   error
+}{
+	{"Authentication failed", ErrAuthFailed},
+	{"could not read Username", ErrAuthFailed},
+	{"could not read Password", ErrAuthFailed},
+	{"Permission denied (publickey", ErrAuthFailed},
+	{"The requested URL returned error: 403", ErrAuthFailed},
+	{"non-fast-forward", ErrNonFastForward},
+	{"(fetch first)", ErrNonFastForward},
+	{"couldn't find remote ref", ErrRefNotFound},
+	{"unknown revision", ErrRefNotFound},
+	{"not a valid object name", ErrRefNotFound},
+	{"did not match any", ErrRefNotFound},
+	{"No names found", ErrRefNotFound},
+	{"No tags can describe", ErrRefNotFound},
+	{"CONFLICT", ErrMergeConflict},
+	{"could not apply", ErrMergeConflict},
+	{"patch does not apply", ErrMergeConflict},
+	{"Could not resolve host", ErrNetwork},
+	{"Connection timed out", ErrNetwork},
+	{"Connection reset", ErrNetwork},
+	{"Connection refused", ErrNetwork},
+	{"Operation timed out", ErrNetwork},
+	{"The requested URL returned error: 5", ErrNetwork},
+	{"RPC failed", ErrNetwork},
+	{"early EOF", ErrNetwork},
+	{"remote end hung up unexpectedly", ErrNetwork},
+	// Reported after any failure to talk to an SSH remote, so this must follow
+	// the network patterns.
+	{"Could not read from remote repository", ErrAuthFailed},
+}
+
+// classify returns err wrapped in an Error if the git stderr output matches
+// one of the known failure reasons, otherwise err.
+func classify(err error, stderr string) error {
+	for _, p := range stderrPatterns {
+		if strings.Contains(stderr, p.substr) {
+			return Error{Kind: p.kind, Err: err}
+		}
+	}
+	return err
+}
+
+// classifyGoGit returns err wrapped in an Error if err is a go-git error for
+// one of the known failure reasons, otherwise err.
+func classifyGoGit(err error) error {
+	var kind error
+	switch err {
+	case transport.ErrAuthenticationRequired, transport.ErrAuthorizationFailed:
+		kind = ErrAuthFailed
+	case gogit.ErrForceNeeded:
+		kind = ErrNonFastForward
+	case plumbing.ErrReferenceNotFound:
+		kind = ErrRefNotFound
+	default:
+		if isTransientGoGit(err) {
+			kind = ErrNetwork
+		}
+	}
+	if kind == nil {
+		return err
+	}
+	return Error{Kind: kind, Err: err}
+}
+
+// isTransientGoGit returns true if the go-git error err is a network failure
+// or an HTTP 5xx response, which may succeed if the operation is retried.
+func isTransientGoGit(err error) bool {
+	if ue, ok := err.(*plumbing.UnexpectedError); ok {
+		err = ue.Err
+	}
+	var httpErr *http.Err
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode() >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	for _, test := range []struct {
+		stderr string
+		expect error // nil if the error should not be classified
+	}{
+		{"", nil},
+		{"fatal: Authentication failed for 'https://github.com/a/b.git/'", ErrAuthFailed},
+		{"fatal: could not read Username for 'https://github.com': terminal prompts disabled", ErrAuthFailed},
+		{"git@github.com: Permission denied (publickey).\r\nfatal: Could not read from remote repository.", ErrAuthFailed},
+		{"fatal: Could not read from remote repository.\n\nPlease make sure you have the correct access rights", ErrAuthFailed},
+		{"error: The requested URL returned error: 403", ErrAuthFailed},
+		{"error: unable to create file CHANGES.md: Permission denied", nil},
+		{"fatal: cannot open '.git/FETCH_HEAD': Permission denied", nil},
+		{" ! [rejected]        main -> main (non-fast-forward)", ErrNonFastForward},
+		{" ! [rejected]        main -> main (fetch first)", ErrNonFastForward},
+		{"fatal: couldn't find remote ref refs/heads/missing", ErrRefNotFound},
+		{"fatal: ambiguous argument 'v9': unknown revision or path not in the working tree.", ErrRefNotFound},
+		{"fatal: No names found, cannot describe anything.", ErrRefNotFound},
+		{"CONFLICT (content): Merge conflict in CHANGES.md", ErrMergeConflict},
+		{"error: could not apply 0123456... Fix the thing", ErrMergeConflict},
+		{"fatal: unable to access 'https://github.com/a/b.git/': Could not resolve host: github.com", ErrNetwork},
+		{"ssh: Could not resolve hostname github.com: Name or service not known\r\nfatal: Could not read from remote repository.", ErrNetwork},
+		{"ssh: connect to host github.com port 22: Connection refused\r\nfatal: Could not read from remote repository.", ErrNetwork},
+		{"error: RPC failed; HTTP 502 curl 22 The requested URL returned error: 502", ErrNetwork},
+		{"fatal: the remote end hung up unexpectedly", ErrNetwork},
+		{"fatal: not a git repository (or any of the parent directories): .git", nil},
+	} {
+		err := fmt.Errorf("exit status 128")
+		got := classify(err, test.stderr)
+		if !errors.Is(got, err) {
+			t.Errorf("classify('%v') returned '%v', which does not wrap the original error", test.stderr, got)
+		}
+		var gitErr Error
+		switch {
+		case test.expect == nil && errors.As(got, &gitErr):
+			t.Errorf("classify('%v') returned kind '%v', expected the error to be unclassified", test.stderr, gitErr.Kind)
+		case test.expect != nil && !errors.Is(got, test.expect):
+			t.Errorf("classify('%v') returned '%v', expected kind '%v'", test.stderr, got, test.expect)
+		}
+	}
+}
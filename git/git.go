@@ -18,10 +18,12 @@ package git
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"net/url"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -32,16 +34,130 @@ const (
 
 // Git provides functions for interacting with git
 type Git struct {
-	exe string
+	exe   string // Path to the git executable. Empty if not found.
+	gogit *goGit // Non-nil if using the go-git backend
+	cfg   Config // Configuration of the git executable
 }
 
+// Config configures how the git executable is run.
+// The go-git backend only uses Config for operations that fall back to the git
+// executable.
+type Config struct {
+	Timeout  time.Duration            // Default timeout of each git command. 15 minutes if zero
+	Timeouts map[string]time.Duration // Timeouts by git command name (e.g. 'fetch'), overriding Timeout
+	Env      []string                 // Additional environment variables of the form 'NAME=value', e.g. 'GIT_TRACE=1'
+	Log      func(cmdline string)     // If non-nil, called with the command line of each git command before it is run
+	Retry    RetryPolicy              // Retry policy of fetches, pushes and ls-remotes. Uses DefaultRetryPolicy if Attempts is 0
+}
+
+// RetryPolicy controls how operations that communicate with a remote are
+// retried after a transient failure. Only errors that wrap ErrNetwork are
+// retried.
+type RetryPolicy struct {
+	Attempts int           // Maximum number of attempts. Operations are not retried if less than 2
+	Delay    time.Duration // Delay before the first retry, doubled for each subsequent retry
+	MaxDelay time.Duration // Maximum delay between attempts. Unlimited if zero
+}
+
+// DefaultRetryPolicy is the RetryPolicy used if Config.Retry is unset.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Delay: 2 * time.Second, MaxDelay: 30 * time.Second}
+
+// after is time.After, replaced by tests to skip the delays between retries.
+var after = time.After
+
+// retry calls f until it returns nil or an error that does not wrap
+// ErrNetwork, or the attempts of the retry policy are exhausted.
+func (g Git) retry(ctx context.Context, f func() error) error {
+	policy := g.cfg.Retry
+	if policy.Attempts == 0 {
+		policy = DefaultRetryPolicy
+	}
+	delay := policy.Delay
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= policy.Attempts || !errors.Is(err, ErrNetwork) {
+			return err
+		}
+		select {
+		case <-after(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay *= 2
+		if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
+
+// remoteCommands are the git commands that communicate with a remote, and are
+// retried on transient failures.
+var remoteCommands = map[string]bool{"fetch": true, "push": true, "ls-remote": true}
+
+// Configure sets the configuration used to run the git executable.
+func (g *Git) Configure(cfg Config) {
+	g.cfg = cfg
+}
+
+// Backend is an enumerator of git implementations.
+type Backend int
+
+const (
+	// Exec uses the git executable.
+	Exec Backend = iota
+	// GoGit uses the pure-Go go-git library. Operations not supported by
+	// go-git fall back to the git executable, if found.
+	GoGit
+	// Auto uses the git executable if found, otherwise GoGit.
+	Auto
+)
+
 // New looks up the git exectable and returns a new Git
 func New() (*Git, error) {
+	return NewWithBackend(Exec)
+}
+
+// NewWithBackend returns a new Git that uses the backend b.
+func NewWithBackend(b Backend) (*Git, error) {
 	path, err := exec.LookPath("git")
-	if err != nil {
-		return nil, fmt.Errorf("Couldn't find path to git executable")
+	switch b {
+	case Exec:
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't find path to git executable")
+		}
+		return &Git{exe: path}, nil
+	case GoGit:
+		return &Git{exe: path, gogit: &goGit{}}, nil
+	case Auto:
+		if err != nil {
+			return &Git{gogit: &goGit{}}, nil
+		}
+		return &Git{exe: path}, nil
+	}
+	return nil, fmt.Errorf("Unknown git backend %v", b)
+}
+
+// ParseBackend parses the backend from s, which is one of 'exec', 'go-git' or
+// 'auto'.
+func ParseBackend(s string) (Backend, error) {
+	switch s {
+	case "exec":
+		return Exec, nil
+	case "go-git":
+		return GoGit, nil
+	case "auto":
+		return Auto, nil
 	}
-	return &Git{path}, nil
+	return 0, fmt.Errorf("Unknown git backend '%v'. Must be one of: exec, go-git, auto", s)
+}
+
+// execFallback returns an error if the git executable is required for the
+// operation op, but was not found.
+func (g Git) execFallback(op string) error {
+	if g.exe == "" {
+		return fmt.Errorf("`git %v` is not supported by the go-git backend, and the git executable was not found", op)
+	}
+	return nil
 }
 
 // Hash is a 20 byte, git object hash.
@@ -58,103 +174,268 @@ func ParseHash(s string) Hash {
 }
 
 // Add calls 'git add <file>'.
-func (g Git) Add(wd, file string) error {
-	if _, err := shell(gitTimeout, g.exe, wd, "add", file); err != nil {
+func (g Git) Add(ctx context.Context, wd, file string) error {
+	if g.gogit != nil {
+		return g.gogit.add(ctx, wd, file)
+	}
+	if _, err := g.run(ctx, wd, "add", file); err != nil {
 		return fmt.Errorf("`git add %v` in working directory %v failed: %w", file, wd, err)
 	}
 	return nil
 }
 
+// SignFlags advanced flags for signing commits and tags.
+// Signing is performed with the git executable, even when using the go-git
+// backend.
+type SignFlags struct {
+	KeyID   string // GPG key used to sign. Uses the configured key if empty
+	SSHKey  string // Path to the SSH key used to sign. Takes precedence over KeyID
+	Program string // Program used to sign, e.g. 'gpg2' or 'ssh-keygen'
+}
+
+// Enabled returns true if commits and tags should be signed.
+func (f SignFlags) Enabled() bool {
+	return f.KeyID != "" || f.SSHKey != "" || f.Program != ""
+}
+
+// configArgs returns the git '-c' arguments used to configure signing.
+func (f SignFlags) configArgs() []string {
+	args := []string{}
+	switch {
+	case f.SSHKey != "":
+		args = append(args, "-c", "gpg.format=ssh", "-c", "user.signingkey="+f.SSHKey)
+		if f.Program != "" {
+			args = append(args, "-c", "gpg.ssh.program="+f.Program)
+		}
+	default:
+		if f.KeyID != "" {
+			args = append(args, "-c", "user.signingkey="+f.KeyID)
+		}
+		if f.Program != "" {
+			args = append(args, "-c", "gpg.program="+f.Program)
+		}
+	}
+	return args
+}
+
 // CommitFlags advanced flags for Commit
 type CommitFlags struct {
-	Name  string // Used for author and committer
-	Email string // Used for author and committer
+	Name  string    // Used for author and committer
+	Email string    // Used for author and committer
+	Sign  SignFlags // If enabled, the commit is signed
 }
 
-// Commit calls 'git commit -m <msg> --author <author>'.
-func (g Git) Commit(wd, msg string, flags CommitFlags) error {
+// configArgs returns the git '-c' arguments used to configure the identity and
+// signing of the commit.
+func (f CommitFlags) configArgs() []string {
 	args := []string{}
-	if flags.Name != "" {
-		args = append(args, "-c", "user.name="+flags.Name)
+	if f.Name != "" {
+		args = append(args, "-c", "user.name="+f.Name)
 	}
-	if flags.Email != "" {
-		args = append(args, "-c", "user.email="+flags.Email)
+	if f.Email != "" {
+		args = append(args, "-c", "user.email="+f.Email)
+	}
+	return append(args, f.Sign.configArgs()...)
+}
+
+// Commit calls 'git commit -m <msg> --author <author>'.
+func (g Git) Commit(ctx context.Context, wd, msg string, flags CommitFlags) error {
+	if g.gogit != nil && !flags.Sign.Enabled() {
+		return g.gogit.commit(ctx, wd, msg, flags)
+	}
+	if err := g.execFallback("commit --gpg-sign"); err != nil {
+		return err
+	}
+	args := append(flags.configArgs(), "commit", "-m", msg)
+	if flags.Sign.Enabled() {
+		args = append(args, "--gpg-sign")
 	}
-	args = append(args, "commit", "-m", msg)
-	_, err := shell(gitTimeout, g.exe, wd, args...)
+	_, err := g.run(ctx, wd, args...)
 	return err
 }
 
-// PushFlags advanced flags for pushing changes, tags.
-type PushFlags struct {
-	Username string // Used for authentication when uploading
-	Password string // Used for authentication when uploading
+// Credentials are used to authenticate with remotes.
+type Credentials struct {
+	Username string // Used for authentication with HTTPS remotes
+	Password string // Used for authentication with HTTPS remotes
+	SSHKey   string // Path to the private key for SSH remotes. Uses ssh-agent if empty
 }
 
-func (f PushFlags) addCredentials(remote string) (string, error) {
-	if f.Username != "" {
-		u, err := url.Parse(remote)
-		if err != nil {
-			return "", fmt.Errorf("Couldn't parse remote URL: %w", err)
+// credentialHelper is a git credential helper that reads the username and
+// password from environment variables, so that they do not appear in the
+// remote URL, process listings or error messages.
+const credentialHelper = `!f() { test "$1" = get && ` +
+	`echo "username=$RELEASE_ME_GIT_USERNAME" && ` +
+	`echo "password=$RELEASE_ME_GIT_PASSWORD"; }; f`
+
+// isSSH returns true if remote is an SSH URL, either of the form
+// 'ssh://[user@]host/path' or the scp-like '[user@]host:path'.
+func isSSH(remote string) bool {
+	if strings.HasPrefix(remote, "ssh://") || strings.HasPrefix(remote, "git+ssh://") {
+		return true
+	}
+	if strings.Contains(remote, "://") {
+		return false
+	}
+	// Single letter hosts are treated as Windows drive letters.
+	i := strings.Index(remote, ":")
+	return i > 1 && !strings.ContainsAny(remote[:i], `/\`)
+}
+
+// args returns the git arguments and environment variables used to
+// authenticate with remote.
+func (c Credentials) args(remote string) (args, env []string) {
+	if isSSH(remote) {
+		if c.SSHKey != "" {
+			key := "'" + strings.ReplaceAll(c.SSHKey, "'", `'\''`) + "'"
+			env = []string{"GIT_SSH_COMMAND=ssh -i " + key + " -o IdentitiesOnly=yes"}
 		}
-		u.User = url.UserPassword(f.Username, f.Password)
-		remote = u.String()
+		return nil, env
 	}
-	return remote, nil
+	if c.Username != "" {
+		// The first, empty helper disables any configured helpers.
+		args = []string{"-c", "credential.helper=", "-c", "credential.helper=" + credentialHelper}
+		env = []string{
+			"RELEASE_ME_GIT_USERNAME=" + c.Username,
+			"RELEASE_ME_GIT_PASSWORD=" + c.Password,
+		}
+	}
+	return args, env
+}
+
+// PushFlags advanced flags for pushing changes, tags.
+type PushFlags struct {
+	Credentials         // Used to authenticate with the remote
+	ForceWithLease Hash // If non-zero, Push overwrites the remote branch only if it is at this hash
+	Force          bool // Overwrite the remote branch or tags, even if they are not ancestors of the local ones
+	DryRun         bool // Do everything except actually send the updates
+}
+
+// needsExec returns true if the flags are not supported by the go-git backend.
+func (f PushFlags) needsExec() bool {
+	return f.ForceWithLease != Hash{} || f.DryRun
 }
 
 // Push pushes the local branch to remote.
-func (g Git) Push(wd, remote, localBranch, remoteBranch string, flags PushFlags) error {
-	remote, err := flags.addCredentials(remote)
-	if err != nil {
+func (g Git) Push(ctx context.Context, wd, remote, localBranch, remoteBranch string, flags PushFlags) error {
+	if g.gogit != nil && !flags.needsExec() {
+		return g.retry(ctx, func() error { return g.gogit.push(ctx, wd, remote, localBranch, remoteBranch, flags) })
+	}
+	if err := g.execFallback("push --force-with-lease --dry-run"); err != nil {
 		return err
 	}
-	_, err = shell(gitTimeout, g.exe, wd, "push", remote, localBranch+":refs/heads/"+remoteBranch)
+	args, env := flags.args(remote)
+	args = append(args, "push")
+	if flags.DryRun {
+		args = append(args, "--dry-run")
+	}
+	if flags.Force {
+		args = append(args, "--force")
+	}
+	if flags.ForceWithLease != (Hash{}) {
+		args = append(args, fmt.Sprintf("--force-with-lease=refs/heads/%v:%v", remoteBranch, flags.ForceWithLease))
+	}
+	args = append(args, remote, localBranch+":refs/heads/"+remoteBranch)
+	_, err := g.runEnv(ctx, wd, env, args...)
 	return err
 }
 
 // PushTags pushes all local tags to remote.
-func (g Git) PushTags(wd, remote string, flags PushFlags) error {
-	remote, err := flags.addCredentials(remote)
-	if err != nil {
+// flags.ForceWithLease is ignored.
+func (g Git) PushTags(ctx context.Context, wd, remote string, flags PushFlags) error {
+	if g.gogit != nil && !flags.DryRun {
+		return g.retry(ctx, func() error { return g.gogit.pushTags(ctx, wd, remote, flags) })
+	}
+	if err := g.execFallback("push --dry-run"); err != nil {
 		return err
 	}
-	_, err = shell(gitTimeout, g.exe, wd, "push", remote, "--tags")
+	args, env := flags.args(remote)
+	args = append(args, "push")
+	if flags.DryRun {
+		args = append(args, "--dry-run")
+	}
+	if flags.Force {
+		args = append(args, "--force")
+	}
+	args = append(args, remote, "--tags")
+	_, err := g.runEnv(ctx, wd, env, args...)
 	return err
 }
 
-// CheckoutRemoteBranch performs a git fetch and checkout of the given branch into path.
-func (g Git) CheckoutRemoteBranch(path, url string, branch string) error {
-	if err := os.MkdirAll(path, 0777); err != nil {
-		return fmt.Errorf("mkdir '%v' failed: %w", path, err)
+// PushTag pushes the local tag with the given name to remote.
+// flags.ForceWithLease is ignored.
+func (g Git) PushTag(ctx context.Context, wd, remote, name string, flags PushFlags) error {
+	if g.gogit != nil && !flags.DryRun {
+		return g.retry(ctx, func() error { return g.gogit.pushTag(ctx, wd, remote, name, flags) })
 	}
-
-	for _, cmds := range [][]string{
-		{"init"},
-		{"fetch", url, branch},
-		{"checkout", "FETCH_HEAD"},
-	} {
-		if _, err := shell(gitTimeout, g.exe, path, cmds...); err != nil {
-			os.RemoveAll(path)
-			return err
-		}
+	if err := g.execFallback("push --dry-run"); err != nil {
+		return err
+	}
+	args, env := flags.args(remote)
+	args = append(args, "push")
+	if flags.DryRun {
+		args = append(args, "--dry-run")
 	}
+	if flags.Force {
+		args = append(args, "--force")
+	}
+	args = append(args, remote, "refs/tags/"+name+":refs/tags/"+name)
+	_, err := g.runEnv(ctx, wd, env, args...)
+	return err
+}
 
-	return nil
+// CheckoutFlags advanced flags for checking out remote branches and commits.
+type CheckoutFlags struct {
+	Credentials        // Used to authenticate with the remote
+	Depth       int    // If greater than 0, limits the fetched history to Depth commits
+	Filter      string // Partial clone filter, e.g. 'blob:none'. Ignored by the go-git backend
+}
+
+// CheckoutRemoteBranch performs a git fetch and checkout of the given branch into path.
+func (g Git) CheckoutRemoteBranch(ctx context.Context, path, url string, branch string, flags CheckoutFlags) error {
+	if g.gogit != nil {
+		return g.retry(ctx, func() error { return g.gogit.checkoutRemoteBranch(ctx, path, url, branch, flags) })
+	}
+	return g.checkoutRemote(ctx, path, url, branch, flags)
 }
 
 // CheckoutRemoteCommit performs a git fetch and checkout of the given commit into path.
-func (g Git) CheckoutRemoteCommit(path, url string, commit Hash) error {
+func (g Git) CheckoutRemoteCommit(ctx context.Context, path, url string, commit Hash, flags CheckoutFlags) error {
+	if g.gogit != nil {
+		return g.retry(ctx, func() error { return g.gogit.checkoutRemoteCommit(ctx, path, url, commit, flags) })
+	}
+	return g.checkoutRemote(ctx, path, url, commit.String(), flags)
+}
+
+// checkoutRemote performs a git fetch and checkout of ref into path.
+// If a partial clone filter is used, then url is registered as the 'origin'
+// promisor remote so that missing objects can be fetched on demand. Note that
+// these on demand fetches are not authenticated with flags.Credentials.
+func (g Git) checkoutRemote(ctx context.Context, path, url, ref string, flags CheckoutFlags) error {
 	if err := os.MkdirAll(path, 0777); err != nil {
 		return fmt.Errorf("mkdir '%v' failed: %w", path, err)
 	}
 
-	for _, cmds := range [][]string{
-		{"init"},
-		{"fetch", url, commit.String()},
-		{"checkout", "FETCH_HEAD"},
-	} {
-		if _, err := shell(gitTimeout, g.exe, path, cmds...); err != nil {
+	cmds := [][]string{{"init"}}
+	fetch, env := flags.args(url)
+	fetch = append(fetch, "fetch")
+	if flags.Depth > 0 {
+		fetch = append(fetch, fmt.Sprintf("--depth=%d", flags.Depth))
+	}
+	if flags.Filter != "" {
+		cmds = append(cmds,
+			[]string{"config", "remote.origin.url", url},
+			[]string{"config", "remote.origin.promisor", "true"},
+			[]string{"config", "remote.origin.partialclonefilter", flags.Filter},
+		)
+		fetch = append(fetch, "--filter="+flags.Filter, "origin", ref)
+	} else {
+		fetch = append(fetch, url, ref)
+	}
+	cmds = append(cmds, fetch, []string{"checkout", "FETCH_HEAD"})
+
+	for _, args := range cmds {
+		if _, err := g.runEnv(ctx, path, env, args...); err != nil {
 			os.RemoveAll(path)
 			return err
 		}
@@ -163,41 +444,202 @@ func (g Git) CheckoutRemoteCommit(path, url string, commit Hash) error {
 	return nil
 }
 
+// TagFlags advanced flags for Tag.
+type TagFlags struct {
+	Message string    // If non-empty, an annotated tag is created with this message
+	Name    string    // Used for the tagger of annotated tags
+	Email   string    // Used for the tagger of annotated tags
+	Force   bool      // Replace any existing tag with the same name
+	Sign    SignFlags // If enabled, an annotated, signed tag is created
+}
+
 // Tag creates a git tag for the given hash.
-func (g Git) Tag(path, name string, at Hash) error {
-	if _, err := shell(gitTimeout, g.exe, path, "tag", name, at.String()); err != nil {
+// Signed tags without a message use the tag name as the message.
+func (g Git) Tag(ctx context.Context, path, name string, at Hash, flags TagFlags) error {
+	if g.gogit != nil && !flags.Sign.Enabled() {
+		return g.gogit.tag(ctx, path, name, at, flags)
+	}
+	if err := g.execFallback("tag --sign"); err != nil {
+		return err
+	}
+	args := []string{}
+	if flags.Name != "" {
+		args = append(args, "-c", "user.name="+flags.Name)
+	}
+	if flags.Email != "" {
+		args = append(args, "-c", "user.email="+flags.Email)
+	}
+	args = append(args, flags.Sign.configArgs()...)
+	args = append(args, "tag")
+	if flags.Force {
+		args = append(args, "--force")
+	}
+	if flags.Sign.Enabled() {
+		if flags.Message == "" {
+			flags.Message = name
+		}
+		args = append(args, "--sign")
+	}
+	if flags.Message != "" {
+		args = append(args, "--annotate", "--message", flags.Message)
+	}
+	args = append(args, name, at.String())
+	if _, err := g.run(ctx, path, args...); err != nil {
 		return err
 	}
 	return nil
 }
 
+// FetchTags fetches all the tags from the remote url into the repo at path.
+func (g Git) FetchTags(ctx context.Context, path, url string, cred Credentials) error {
+	if g.gogit != nil {
+		return g.retry(ctx, func() error { return g.gogit.fetchTags(ctx, path, url, cred) })
+	}
+	args, env := cred.args(url)
+	args = append(args, "fetch", url, "+refs/tags/*:refs/tags/*")
+	_, err := g.runEnv(ctx, path, env, args...)
+	return err
+}
+
+// VerifyTag verifies the signature of the tag with the given name, returning
+// an error if the tag is unsigned or the signature is not valid.
+// flags.Program and flags.SSHKey are used to select the verification program.
+func (g Git) VerifyTag(ctx context.Context, path, name string, flags SignFlags) error {
+	if err := g.execFallback("tag --verify"); err != nil {
+		return err
+	}
+	args := append(flags.configArgs(), "tag", "--verify", name)
+	_, err := g.run(ctx, path, args...)
+	return err
+}
+
 // Rebase performs a git rebase of the current branch onto to.
-func (g Git) Rebase(path string, to Hash) error {
-	if _, err := shell(gitTimeout, g.exe, path, "rebase", to.String()); err != nil {
+func (g Git) Rebase(ctx context.Context, path string, to Hash) error {
+	if err := g.execFallback("rebase"); err != nil {
+		return err
+	}
+	if _, err := g.run(ctx, path, "rebase", to.String()); err != nil {
 		return err
 	}
 	return nil
 }
 
 // CheckoutCommit performs a git checkout of the given commit.
-func (g Git) CheckoutCommit(path string, commit Hash) error {
-	_, err := shell(gitTimeout, g.exe, path, "checkout", commit.String())
+func (g Git) CheckoutCommit(ctx context.Context, path string, commit Hash) error {
+	if g.gogit != nil {
+		return g.gogit.checkoutCommit(ctx, path, commit)
+	}
+	_, err := g.run(ctx, path, "checkout", commit.String())
 	return err
 }
 
 // Apply applys the patch file to the git repo at dir.
-func (g Git) Apply(dir, patch string) error {
-	_, err := shell(gitTimeout, g.exe, dir, "apply", patch)
+func (g Git) Apply(ctx context.Context, dir, patch string) error {
+	if err := g.execFallback("apply"); err != nil {
+		return err
+	}
+	_, err := g.run(ctx, dir, "apply", patch)
 	return err
 }
 
 // FetchRefHash returns the git hash of the given ref.
-func (g Git) FetchRefHash(ref, url string) (Hash, error) {
-	out, err := shell(gitTimeout, g.exe, "", "ls-remote", url, ref)
+// ref may be a full reference name, or a branch or tag name.
+func (g Git) FetchRefHash(ctx context.Context, ref, url string) (Hash, error) {
+	refs, err := g.lsRemote(ctx, url, Credentials{}, ref)
 	if err != nil {
 		return Hash{}, err
 	}
-	return ParseHash(string(out)), nil
+	return findRef(refs, ref), nil
+}
+
+// findRef returns the hash of ref in refs, which may be a full reference name,
+// or the name of a branch or tag. findRef returns a zero hash if ref is not
+// found.
+func findRef(refs map[string]Hash, ref string) Hash {
+	for _, name := range []string{ref, "refs/heads/" + ref, "refs/tags/" + ref} {
+		if hash, ok := refs[name]; ok {
+			return hash
+		}
+	}
+	return Hash{}
+}
+
+// LsRemoteHeads returns the branches of the remote url, as a map of branch
+// name to commit hash.
+func (g Git) LsRemoteHeads(ctx context.Context, url string, cred Credentials) (map[string]Hash, error) {
+	return g.lsRemoteWithPrefix(ctx, url, cred, "refs/heads/")
+}
+
+// LsRemoteTags returns the tags of the remote url, as a map of tag name to
+// the hash of the tagged object. Annotated tags are peeled.
+func (g Git) LsRemoteTags(ctx context.Context, url string, cred Credentials) (map[string]Hash, error) {
+	return g.lsRemoteWithPrefix(ctx, url, cred, "refs/tags/")
+}
+
+// lsRemoteWithPrefix returns the references of the remote url that start with
+// prefix, as a map of reference name, without the prefix, to hash.
+func (g Git) lsRemoteWithPrefix(ctx context.Context, url string, cred Credentials, prefix string) (map[string]Hash, error) {
+	refs, err := g.lsRemote(ctx, url, cred, prefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]Hash{}
+	for name, hash := range refs {
+		if strings.HasPrefix(name, prefix) {
+			out[strings.TrimPrefix(name, prefix)] = hash
+		}
+	}
+	return out, nil
+}
+
+// lsRemote returns the references of the remote url, as a map of full
+// reference name to hash. Annotated tags are peeled. patterns may be used to
+// limit the references returned by the git executable, but are ignored by the
+// go-git backend.
+func (g Git) lsRemote(ctx context.Context, url string, cred Credentials, patterns ...string) (map[string]Hash, error) {
+	if g.gogit != nil {
+		var refs map[string]Hash
+		err := g.retry(ctx, func() (err error) {
+			refs, err = g.gogit.lsRemote(ctx, url, cred)
+			return err
+		})
+		return refs, err
+	}
+	args, env := cred.args(url)
+	args = append(args, "ls-remote", url)
+	args = append(args, patterns...)
+	out, err := g.runEnv(ctx, "", env, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseLsRemote(string(out)), nil
+}
+
+// parseLsRemote parses the output of 'git ls-remote', returning a map of
+// reference name to hash. Annotated tags are peeled. Malformed lines are
+// ignored.
+func parseLsRemote(out string) map[string]Hash {
+	refs := map[string]Hash{}
+	peeled := map[string]Hash{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if b, err := hex.DecodeString(fields[0]); err != nil || len(b) != len(Hash{}) {
+			continue
+		}
+		hash, name := ParseHash(fields[0]), fields[1]
+		if strings.HasSuffix(name, "^{}") {
+			peeled[strings.TrimSuffix(name, "^{}")] = hash
+		} else {
+			refs[name] = hash
+		}
+	}
+	for name, hash := range peeled {
+		refs[name] = hash
+	}
+	return refs
 }
 
 type ChangeList struct {
@@ -208,45 +650,275 @@ type ChangeList struct {
 	Description string
 }
 
+// LogFlags advanced flags for LogFrom.
+type LogFlags struct {
+	Paths       []string // If non-empty, only commits that change these paths are returned
+	Excludes    []string // Commits that only change these paths are omitted
+	FirstParent bool     // Only follow the first parent of merge commits
+	NoMerges    bool     // Omit merge commits
+}
+
 // Log returns the top count ChangeLists at HEAD, starting with the most recent.
-func (g Git) Log(wd, path string, count int) ([]ChangeList, error) {
-	return g.LogFrom(wd, path, "HEAD", count)
+// If path is empty, then the log is not filtered to a path.
+func (g Git) Log(ctx context.Context, wd, path string, count int) ([]ChangeList, error) {
+	flags := LogFlags{}
+	if path != "" {
+		flags.Paths = []string{path}
+	}
+	return g.LogFrom(ctx, wd, "HEAD", count, flags)
 }
 
 // LogFrom returns the top count ChangeList starting from at, starting with the
-// most recent.
-func (g Git) LogFrom(wd, path, at string, count int) ([]ChangeList, error) {
+// most recent. at may also be a revision range (e.g. 'v1.0.0..HEAD').
+func (g Git) LogFrom(ctx context.Context, wd, at string, count int, flags LogFlags) ([]ChangeList, error) {
+	if g.gogit != nil {
+		return g.gogit.logFrom(ctx, wd, at, count, flags)
+	}
 	if at == "" {
 		at = "HEAD"
 	}
-	args := []string{"log", at, "--pretty=format:" + prettyFormat}
+	args := []string{"log", "-z", at, "--pretty=format:" + prettyFormat}
 	if count > 0 {
 		args = append(args, fmt.Sprintf("-%d", count))
 	}
-	args = append(args, path)
-	out, err := shell(gitTimeout, g.exe, wd, args...)
+	if flags.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	if flags.NoMerges {
+		args = append(args, "--no-merges")
+	}
+	if len(flags.Paths) > 0 || len(flags.Excludes) > 0 {
+		args = append(args, "--")
+		args = append(args, flags.Paths...)
+		for _, path := range flags.Excludes {
+			args = append(args, ":(exclude)"+path)
+		}
+	}
+	out, err := g.run(ctx, wd, args...)
 	if err != nil {
 		return nil, err
 	}
-	return parseLog(string(out)), nil
+	return parseLog(string(out))
 }
 
 // Parent returns the parent ChangeList for cl.
-func (g Git) Parent(cl ChangeList) (ChangeList, error) {
-	out, err := shell(gitTimeout, g.exe, "", "log", "--pretty=format:"+prettyFormat, fmt.Sprintf("%v^", cl.Hash))
+func (g Git) Parent(ctx context.Context, cl ChangeList) (ChangeList, error) {
+	if g.gogit != nil {
+		return g.gogit.parent(ctx, cl)
+	}
+	out, err := g.run(ctx, "", "log", "-z", "--pretty=format:"+prettyFormat, fmt.Sprintf("%v^", cl.Hash))
+	if err != nil {
+		return ChangeList{}, err
+	}
+	cls, err := parseLog(string(out))
 	if err != nil {
 		return ChangeList{}, err
 	}
-	cls := parseLog(string(out))
 	if len(cls) == 0 {
 		return ChangeList{}, fmt.Errorf("Unexpected output")
 	}
 	return cls[0], nil
 }
 
+// MergeBase returns the best common ancestor of the commits a and b.
+func (g Git) MergeBase(ctx context.Context, wd string, a, b Hash) (Hash, error) {
+	if g.gogit != nil {
+		return g.gogit.mergeBase(ctx, wd, a, b)
+	}
+	out, err := g.run(ctx, wd, "merge-base", a.String(), b.String())
+	if err != nil {
+		return Hash{}, fmt.Errorf("Failed to find merge base of %v and %v: %w", a, b, err)
+	}
+	return ParseHash(strings.TrimSpace(string(out))), nil
+}
+
+// IsAncestor returns true if the commit ancestor is an ancestor of the commit
+// descendant. A commit is considered to be an ancestor of itself.
+func (g Git) IsAncestor(ctx context.Context, wd string, ancestor, descendant Hash) (bool, error) {
+	if g.gogit != nil {
+		return g.gogit.isAncestor(ctx, wd, ancestor, descendant)
+	}
+	_, err := g.run(ctx, wd, "merge-base", "--is-ancestor", ancestor.String(), descendant.String())
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// FileStat holds the number of lines changed in a single file.
+type FileStat struct {
+	Path    string // Repo-relative path of the file
+	Added   int    // Number of lines added
+	Deleted int    // Number of lines deleted
+	Binary  bool   // True if the file is binary. Added and Deleted are 0
+}
+
+// DiffStat summarizes the changes between two commits.
+type DiffStat struct {
+	Files   []FileStat // The changed files
+	Added   int        // Total number of lines added
+	Deleted int        // Total number of lines deleted
+}
+
+func (s DiffStat) String() string {
+	return fmt.Sprintf("%d files changed, %d insertions(+), %d deletions(-)", len(s.Files), s.Added, s.Deleted)
+}
+
+// ChangedFiles returns the repo-relative paths of the files that differ
+// between the commits from and to. Renamed files are reported as a deletion
+// of the old path and an addition of the new path.
+func (g Git) ChangedFiles(ctx context.Context, wd string, from, to Hash) ([]string, error) {
+	if g.gogit != nil {
+		return g.gogit.changedFiles(ctx, wd, from, to)
+	}
+	out, err := g.run(ctx, wd, "diff", "--no-renames", "--name-only", "-z", from.String(), to.String())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to diff %v and %v: %w", from, to, err)
+	}
+	files := []string{}
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// DiffStat returns the number of lines changed between the commits from and
+// to.
+func (g Git) DiffStat(ctx context.Context, wd string, from, to Hash) (DiffStat, error) {
+	if g.gogit != nil {
+		return g.gogit.diffStat(ctx, wd, from, to)
+	}
+	out, err := g.run(ctx, wd, "diff", "--no-renames", "--numstat", "-z", from.String(), to.String())
+	if err != nil {
+		return DiffStat{}, fmt.Errorf("Failed to diff %v and %v: %w", from, to, err)
+	}
+	return parseNumstat(string(out))
+}
+
+// parseNumstat parses the output of 'git diff --no-renames --numstat -z'.
+// Each file is a NUL terminated line of '<added>\t<deleted>\t<path>', where
+// binary files use '-' for the line counts. Renames, which are only reported if
+// rename detection is enabled, have an empty path followed by the NUL
+// terminated old and new paths.
+func parseNumstat(str string) (DiffStat, error) {
+	stat := DiffStat{Files: []FileStat{}}
+	fields := strings.Split(str, "\x00")
+	for i := 0; i < len(fields); i++ {
+		line := fields[i]
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			return DiffStat{}, fmt.Errorf("Unexpected git diff output: '%v'", line)
+		}
+		file := FileStat{Path: parts[2]}
+		if file.Path == "" { // Rename: '<added>\t<deleted>\t\0<old>\0<new>'
+			if i+2 >= len(fields) || fields[i+1] == "" || fields[i+2] == "" {
+				return DiffStat{}, fmt.Errorf("Unexpected git diff output: '%v'", line)
+			}
+			file.Path = fields[i+2]
+			i += 2
+		}
+		if parts[0] == "-" && parts[1] == "-" {
+			file.Binary = true
+		} else {
+			var err error
+			if file.Added, err = strconv.Atoi(parts[0]); err != nil {
+				return DiffStat{}, fmt.Errorf("Unexpected git diff output: '%v'", line)
+			}
+			if file.Deleted, err = strconv.Atoi(parts[1]); err != nil {
+				return DiffStat{}, fmt.Errorf("Unexpected git diff output: '%v'", line)
+			}
+		}
+		stat.Files = append(stat.Files, file)
+		stat.Added += file.Added
+		stat.Deleted += file.Deleted
+	}
+	return stat, nil
+}
+
+// TagDescription describes a commit relative to the nearest tag reachable
+// from it.
+type TagDescription struct {
+	Tag     string // Name of the nearest tag
+	Commits int    // Number of commits since the tag. 0 if the commit is tagged
+	Hash    Hash   // Hash of the described commit. Zero if only the tag name is known
+}
+
+// String returns the description in the form used by 'git describe --tags'.
+func (d TagDescription) String() string {
+	if d.Commits == 0 {
+		return d.Tag
+	}
+	return fmt.Sprintf("%v-%d-g%v", d.Tag, d.Commits, d.Hash.String()[:7])
+}
+
+// Describe returns the output of 'git describe --tags' for HEAD of the repo at
+// wd. If no tag is reachable from HEAD, then Describe returns an error that
+// wraps ErrRefNotFound.
+func (g Git) Describe(ctx context.Context, wd string) (string, error) {
+	if g.gogit != nil {
+		d, err := g.gogit.nearestTag(ctx, wd, "HEAD")
+		if err != nil {
+			return "", err
+		}
+		return d.String(), nil
+	}
+	out, err := g.run(ctx, wd, "describe", "--tags")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// NearestTag returns the nearest tag reachable from ref, a branch, tag or
+// commit hash, and the number of commits since that tag. If no tag is
+// reachable from ref, then NearestTag returns an error that wraps
+// ErrRefNotFound.
+func (g Git) NearestTag(ctx context.Context, wd, ref string) (TagDescription, error) {
+	if g.gogit != nil {
+		return g.gogit.nearestTag(ctx, wd, ref)
+	}
+	out, err := g.run(ctx, wd, "describe", "--tags", "--long", "--abbrev=40", ref)
+	if err != nil {
+		return TagDescription{}, err
+	}
+	return parseDescribe(strings.TrimSpace(string(out)))
+}
+
+// describeRE matches the output of 'git describe --long --abbrev=40'.
+var describeRE = regexp.MustCompile(`^(.+)-(\d+)-g([0-9a-f]{40})$`)
+
+// parseDescribe parses the output of 'git describe --long --abbrev=40', which
+// has the form '<tag>-<commits>-g<hash>'. Tag names may contain dashes. Output
+// without the '-<commits>-g<hash>' suffix is the name of a tag that exactly
+// matches the described commit, in which case the returned Hash is zero.
+func parseDescribe(str string) (TagDescription, error) {
+	if str == "" || strings.ContainsAny(str, " \t\r\n") {
+		return TagDescription{}, fmt.Errorf("Unexpected git describe output: '%v'", str)
+	}
+	m := describeRE.FindStringSubmatch(str)
+	if len(m) == 0 {
+		return TagDescription{Tag: str}, nil
+	}
+	commits, err := strconv.Atoi(m[2])
+	if err != nil {
+		return TagDescription{}, fmt.Errorf("Unexpected git describe output: '%v'", str)
+	}
+	return TagDescription{Tag: m[1], Commits: commits, Hash: ParseHash(m[3])}, nil
+}
+
 // HeadCL returns the HEAD ChangeList.
-func (g Git) HeadCL(wd string) (ChangeList, error) {
-	cls, err := g.LogFrom(wd, wd, "HEAD", 1)
+func (g Git) HeadCL(ctx context.Context, wd string) (ChangeList, error) {
+	cls, err := g.LogFrom(ctx, wd, "HEAD", 1, LogFlags{Paths: []string{wd}})
 	if err != nil {
 		return ChangeList{}, err
 	}
@@ -257,50 +929,147 @@ func (g Git) HeadCL(wd string) (ChangeList, error) {
 }
 
 // Show content of the file at path for the given commit/tag/branch.
-func (g Git) Show(wd, path, at string) ([]byte, error) {
-	return shell(gitTimeout, g.exe, wd, "show", at+":"+path)
-}
-
-const prettyFormat = "ǁ%Hǀ%cIǀ%an <%ae>ǀ%sǀ%b"
-
-func parseLog(str string) []ChangeList {
-	msgs := strings.Split(str, "ǁ")
-	cls := make([]ChangeList, 0, len(msgs))
-	for _, s := range msgs {
-		if parts := strings.Split(s, "ǀ"); len(parts) == 5 {
-			cl := ChangeList{
-				Hash:        ParseHash(parts[0]),
-				Author:      strings.TrimSpace(parts[2]),
-				Subject:     strings.TrimSpace(parts[3]),
-				Description: strings.TrimSpace(parts[4]),
-			}
-			date, err := time.Parse(time.RFC3339, parts[1])
-			if err != nil {
-				panic(err)
-			}
-			cl.Date = date
+func (g Git) Show(ctx context.Context, wd, path, at string) ([]byte, error) {
+	if g.gogit != nil {
+		return g.gogit.show(ctx, wd, path, at)
+	}
+	return g.run(ctx, wd, "show", at+":"+path)
+}
+
+// prettyFormat is the 'git log --pretty=format:' used by parseLog. Fields are
+// separated by NUL characters, which cannot appear in commit messages.
+const prettyFormat = "%H%x00%cI%x00%an <%ae>%x00%s%x00%b"
+
+// logFields is the number of fields in prettyFormat.
+const logFields = 5
+
+// parseLog parses the output of 'git log -z --pretty=format:<prettyFormat>'.
+// The -z flag separates each commit with a NUL character, so the output is
+// a flat list of NUL separated fields.
+func parseLog(str string) ([]ChangeList, error) {
+	str = strings.TrimSuffix(str, "\n")
+	if str == "" {
+		return []ChangeList{}, nil
+	}
+	fields := strings.Split(str, "\x00")
+	if len(fields)%logFields != 0 {
+		return nil, fmt.Errorf("Unexpected git log output: %d fields is not a multiple of %d", len(fields), logFields)
+	}
+	cls := make([]ChangeList, 0, len(fields)/logFields)
+	for i := 0; i < len(fields); i += logFields {
+		f := fields[i : i+logFields]
+		hash := strings.TrimSpace(f[0])
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != len(Hash{}) {
+			return nil, fmt.Errorf("Unexpected git log output: invalid commit hash '%v'", hash)
+		}
+		date, err := time.Parse(time.RFC3339, f[1])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse date of commit %v: %w", hash, err)
+		}
+		cls = append(cls, ChangeList{
+			Hash:        ParseHash(hash),
+			Date:        date,
+			Author:      strings.TrimSpace(f[2]),
+			Subject:     strings.TrimSpace(f[3]),
+			Description: strings.TrimSpace(f[4]),
+		})
+	}
+	return cls, nil
+}
+
+// run runs the git executable with the given arguments, in the working
+// directory wd, with the timeout configured for the git command.
+func (g Git) run(ctx context.Context, wd string, args ...string) ([]byte, error) {
+	return g.runEnv(ctx, wd, nil, args...)
+}
 
-			cls = append(cls, cl)
+// runEnv is like run, but adds env to the environment of the process, after
+// the configured environment variables.
+// Commands that communicate with a remote are retried on transient failures.
+func (g Git) runEnv(ctx context.Context, wd string, env []string, args ...string) ([]byte, error) {
+	env = append(append([]string{}, g.cfg.Env...), env...)
+	args = append(append([]string{}, platformArgs...), args...)
+	run := func() ([]byte, error) {
+		if g.cfg.Log != nil {
+			g.cfg.Log(commandLine(g.exe, wd, args))
 		}
+		return shellEnv(ctx, g.timeout(args), g.exe, wd, env, args...)
+	}
+	if !remoteCommands[command(args)] {
+		return run()
 	}
-	return cls
+	var out []byte
+	err := g.retry(ctx, func() (err error) {
+		out, err = run()
+		return err
+	})
+	return out, err
 }
 
-// shell runs the executable exe with the given arguments, in the working
-// directory wd, with the given timeout.
-func shell(timeout time.Duration, exe, wd string, args ...string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+// timeout returns the timeout of the git command with the given arguments.
+func (g Git) timeout(args []string) time.Duration {
+	if t, ok := g.cfg.Timeouts[command(args)]; ok {
+		return t
+	}
+	if g.cfg.Timeout > 0 {
+		return g.cfg.Timeout
+	}
+	return gitTimeout
+}
+
+// command returns the name of the git command in args, skipping any global
+// options, e.g. 'fetch' for '-c name=value fetch origin'.
+func command(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "-c" || a == "-C":
+			i++ // Skip the option's value
+		case strings.HasPrefix(a, "-"):
+		default:
+			return a
+		}
+	}
+	return ""
+}
+
+// commandLine returns the command line of exe with args, run in wd, quoting
+// any arguments that contain whitespace or quotes.
+func commandLine(exe, wd string, args []string) string {
+	sb := strings.Builder{}
+	if wd != "" {
+		fmt.Fprintf(&sb, "(in %v) ", wd)
+	}
+	sb.WriteString(exe)
+	for _, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"") {
+			fmt.Fprintf(&sb, " %q", a)
+		} else {
+			sb.WriteString(" " + a)
+		}
+	}
+	return sb.String()
+}
+
+// shellEnv runs the executable exe with the given arguments, in the working
+// directory wd, with the given timeout, adding env to the environment of the
+// process. The process is killed if ctx is cancelled.
+func shellEnv(ctx context.Context, timeout time.Duration, exe, wd string, env []string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, exe, args...)
 	cmd.Dir = wd
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	out, err := cmd.Output()
 	switch err := err.(type) {
 	case nil:
 		return out, nil
 	case *exec.ExitError:
-		return nil, fmt.Errorf("%v returned with %w\nstderr: %v\nstdout: %v", exe, err, string(err.Stderr), string(out))
+		msg := fmt.Errorf("%v returned with %w\nstderr: %v\nstdout: %v", exe, err, string(err.Stderr), string(out))
+		return nil, classify(msg, string(err.Stderr))
 	default:
 		return nil, fmt.Errorf("%v returned with %w\nstdout: %v", exe, err, string(out))
 	}
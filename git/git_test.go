@@ -0,0 +1,611 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+const (
+	hashA = "0123456789abcdef0123456789abcdef01234567"
+	hashB = "89abcdef0123456789abcdef0123456789abcdef"
+)
+
+// logOutput returns the 'git log -z' output for the commits, each of which is
+// a list of the prettyFormat fields.
+func logOutput(commits ...[]string) string {
+	fields := []string{}
+	for _, c := range commits {
+		fields = append(fields, c...)
+	}
+	return strings.Join(fields, "\x00") + "\n"
+}
+
+func TestParseLog(t *testing.T) {
+	date := time.Date(2020, 6, 1, 12, 30, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name   string
+		log    string
+		expect []ChangeList
+		err    string
+	}{
+		{
+			name:   "empty",
+			log:    "",
+			expect: []ChangeList{},
+		}, {
+			name:   "newline",
+			log:    "\n",
+			expect: []ChangeList{},
+		}, {
+			name: "single",
+			log:  logOutput([]string{hashA, "2020-06-01T12:30:00Z", "A <a@x.com>", "Subject", "Body\n"}),
+			expect: []ChangeList{
+				{Hash: ParseHash(hashA), Date: date, Author: "A <a@x.com>", Subject: "Subject", Description: "Body"},
+			},
+		}, {
+			name: "multiple",
+			log: logOutput(
+				[]string{hashA, "2020-06-01T12:30:00Z", "A <a@x.com>", "First", ""},
+				[]string{"\n" + hashB, "2020-06-01T12:30:00Z", "B <b@x.com>", "Second", "Line 1\n\nLine 2\n"}),
+			expect: []ChangeList{
+				{Hash: ParseHash(hashA), Date: date, Author: "A <a@x.com>", Subject: "First"},
+				{Hash: ParseHash(hashB), Date: date, Author: "B <b@x.com>", Subject: "Second", Description: "Line 1\n\nLine 2"},
+			},
+		}, {
+			name: "separator-like characters",
+			log:  logOutput([]string{hashA, "2020-06-01T12:30:00Z", "A <a@x.com>", "%x00 | %H", "--\n%n\\0 \x01\ncommit " + hashB}),
+			expect: []ChangeList{
+				{Hash: ParseHash(hashA), Date: date, Author: "A <a@x.com>", Subject: "%x00 | %H", Description: "--\n%n\\0 \x01\ncommit " + hashB},
+			},
+		}, {
+			name: "bad date",
+			log:  logOutput([]string{hashA, "1st June 2020", "A <a@x.com>", "Subject", ""}),
+			err:  "Failed to parse date of commit " + hashA,
+		}, {
+			name: "bad hash",
+			log:  logOutput([]string{"xyz", "2020-06-01T12:30:00Z", "A <a@x.com>", "Subject", ""}),
+			err:  "invalid commit hash 'xyz'",
+		}, {
+			name: "short hash",
+			log:  logOutput([]string{hashA[:7], "2020-06-01T12:30:00Z", "A <a@x.com>", "Subject", ""}),
+			err:  "invalid commit hash '" + hashA[:7] + "'",
+		}, {
+			name: "missing fields",
+			log:  logOutput([]string{hashA, "2020-06-01T12:30:00Z", "A <a@x.com>"}),
+			err:  "3 fields is not a multiple of 5",
+		},
+	} {
+		got, err := parseLog(test.log)
+		switch {
+		case test.err != "":
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%v: parseLog() returned error '%v', expected error containing '%v'", test.name, err, test.err)
+			}
+		case err != nil:
+			t.Errorf("%v: parseLog() returned error: %v", test.name, err)
+		case !reflect.DeepEqual(got, test.expect):
+			t.Errorf("%v: parseLog() returned:\n%+v\nexpected:\n%+v", test.name, got, test.expect)
+		}
+	}
+}
+
+// TestParseLogRandom checks that parseLog does not panic on arbitrary input,
+// and that any successfully parsed ChangeLists survive formatting and parsing
+// again.
+func TestParseLogRandom(t *testing.T) {
+	roundTrip := func(data []byte) bool {
+		cls, err := parseLog(string(data))
+		if err != nil {
+			return true
+		}
+		commits := [][]string{}
+		for _, cl := range cls {
+			commits = append(commits, []string{
+				cl.Hash.String(), cl.Date.Format(time.RFC3339), cl.Author, cl.Subject, cl.Description,
+			})
+		}
+		got, err := parseLog(logOutput(commits...))
+		if err != nil || len(got) != len(cls) {
+			return false
+		}
+		for i := range cls {
+			if got[i].Hash != cls[i].Hash || !got[i].Date.Equal(cls[i].Date) ||
+				got[i].Author != cls[i].Author || got[i].Subject != cls[i].Subject ||
+				got[i].Description != cls[i].Description {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Random messages are unlikely to produce valid log output, so also check
+	// well-formed logs with arbitrary authors, subjects and descriptions.
+	messages := func(author, subject, description string) bool {
+		author = strings.Replace(author, "\x00", "", -1)
+		subject = strings.Replace(subject, "\x00", "", -1)
+		description = strings.Replace(description, "\x00", "", -1)
+		cls, err := parseLog(logOutput([]string{hashA, "2020-06-01T12:30:00Z", author, subject, description}))
+		return err == nil && len(cls) == 1 &&
+			cls[0].Author == strings.TrimSpace(author) &&
+			cls[0].Subject == strings.TrimSpace(subject) &&
+			cls[0].Description == strings.TrimSpace(description) &&
+			roundTrip([]byte(logOutput([]string{hashA, "2020-06-01T12:30:00Z", author, subject, description})))
+	}
+	if err := quick.Check(messages, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseLsRemote(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		out    string
+		expect map[string]Hash
+	}{
+		{
+			name:   "empty",
+			out:    "",
+			expect: map[string]Hash{},
+		}, {
+			name: "multiple refs",
+			out: hashA + "\tHEAD\n" +
+				hashA + "\trefs/heads/main\n" +
+				hashB + "\trefs/heads/release-1.2\n",
+			expect: map[string]Hash{
+				"HEAD":                   ParseHash(hashA),
+				"refs/heads/main":        ParseHash(hashA),
+				"refs/heads/release-1.2": ParseHash(hashB),
+			},
+		}, {
+			name: "peeled tag",
+			out: hashA + "\trefs/tags/v1.0.0\n" +
+				hashB + "\trefs/tags/v1.0.0^{}\n",
+			expect: map[string]Hash{
+				"refs/tags/v1.0.0": ParseHash(hashB),
+			},
+		}, {
+			name: "peeled tag first",
+			out: hashB + "\trefs/tags/v1.0.0^{}\n" +
+				hashA + "\trefs/tags/v1.0.0\n",
+			expect: map[string]Hash{
+				"refs/tags/v1.0.0": ParseHash(hashB),
+			},
+		}, {
+			name: "trailing blank lines",
+			out:  hashA + "\trefs/heads/main\n\n\n",
+			expect: map[string]Hash{
+				"refs/heads/main": ParseHash(hashA),
+			},
+		}, {
+			name: "malformed lines",
+			out: "warning: redirecting to https://example.com/repo.git/\n" +
+				hashA + "\n" +
+				"xyz\trefs/heads/bad-hash\n" +
+				hashA[:10] + "\trefs/heads/short-hash\n" +
+				hashA + "\trefs/heads/main\textra\n" +
+				hashB + "\trefs/heads/good\n",
+			expect: map[string]Hash{
+				"refs/heads/good": ParseHash(hashB),
+			},
+		},
+	} {
+		if got := parseLsRemote(test.out); !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("%v: parseLsRemote() returned:\n%+v\nexpected:\n%+v", test.name, got, test.expect)
+		}
+	}
+}
+
+func TestFindRef(t *testing.T) {
+	refs := parseLsRemote(hashA + "\trefs/heads/main\n" +
+		hashA + "\trefs/tags/v1.0.0\n" +
+		hashB + "\trefs/tags/v1.0.0^{}\n" +
+		hashB + "\trefs/heads/both\n" +
+		hashA + "\trefs/tags/both\n")
+	for _, test := range []struct {
+		ref    string
+		expect Hash
+	}{
+		{"main", ParseHash(hashA)},
+		{"refs/heads/main", ParseHash(hashA)},
+		{"v1.0.0", ParseHash(hashB)},
+		{"refs/tags/v1.0.0", ParseHash(hashB)},
+		{"both", ParseHash(hashB)},
+		{"missing", Hash{}},
+	} {
+		if got := findRef(refs, test.ref); got != test.expect {
+			t.Errorf("findRef('%v') returned %v, expected %v", test.ref, got, test.expect)
+		}
+	}
+}
+
+func TestParseNumstat(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		out    string
+		expect DiffStat
+		err    string
+	}{
+		{
+			name:   "empty",
+			out:    "",
+			expect: DiffStat{Files: []FileStat{}},
+		}, {
+			name: "multiple files",
+			out:  "3\t1\tREADME.md\x000\t7\tsrc/old.go\x00",
+			expect: DiffStat{
+				Files: []FileStat{
+					{Path: "README.md", Added: 3, Deleted: 1},
+					{Path: "src/old.go", Deleted: 7},
+				},
+				Added:   3,
+				Deleted: 8,
+			},
+		}, {
+			name: "binary",
+			out:  "-\t-\timage.png\x002\t0\tCHANGES.md\x00",
+			expect: DiffStat{
+				Files: []FileStat{
+					{Path: "image.png", Binary: true},
+					{Path: "CHANGES.md", Added: 2},
+				},
+				Added: 2,
+			},
+		}, {
+			name: "path with tab",
+			out:  "1\t1\ta\tb.txt\x00",
+			expect: DiffStat{
+				Files:   []FileStat{{Path: "a\tb.txt", Added: 1, Deleted: 1}},
+				Added:   1,
+				Deleted: 1,
+			},
+		}, {
+			name: "rename",
+			out:  "4\t2\t\x00old/name.go\x00new/name.go\x001\t0\tother.go\x00",
+			expect: DiffStat{
+				Files: []FileStat{
+					{Path: "new/name.go", Added: 4, Deleted: 2},
+					{Path: "other.go", Added: 1},
+				},
+				Added:   5,
+				Deleted: 2,
+			},
+		}, {
+			name: "truncated rename",
+			out:  "4\t2\t\x00old/name.go\x00",
+			err:  "Unexpected git diff output",
+		}, {
+			name: "missing path",
+			out:  "4\t2\x00",
+			err:  "Unexpected git diff output",
+		}, {
+			name: "bad count",
+			out:  "four\t2\tfile.go\x00",
+			err:  "Unexpected git diff output",
+		},
+	} {
+		got, err := parseNumstat(test.out)
+		switch {
+		case test.err != "":
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%v: parseNumstat() returned error '%v', expected error containing '%v'", test.name, err, test.err)
+			}
+		case err != nil:
+			t.Errorf("%v: parseNumstat() returned error: %v", test.name, err)
+		case !reflect.DeepEqual(got, test.expect):
+			t.Errorf("%v: parseNumstat() returned:\n%+v\nexpected:\n%+v", test.name, got, test.expect)
+		}
+	}
+}
+
+// TestDiffStatBackends checks that the git executable and go-git backends
+// agree on the DiffStat between two commits.
+func TestDiffStatBackends(t *testing.T) {
+	r := newTestRepo(t)
+	defer os.RemoveAll(r.dir)
+
+	from := r.commit("from", map[string]string{
+		"no-newline.txt": "one\ntwo",
+		"edited.txt":     "one\ntwo\nthree\n",
+		"removed.txt":    "one\ntwo\n",
+		"binary.bin":     "\x00\x01\x02",
+		"renamed.txt":    "one\ntwo\nthree\nfour\n",
+	})
+	r.git("mv", "renamed.txt", "moved.txt")
+	to := r.commit("to", map[string]string{
+		"no-newline.txt": "one\nTWO",
+		"edited.txt":     "one\n2\nthree\nfour\nfive",
+		"removed.txt":    "",
+		"binary.bin":     "\x00\x03",
+		"added.txt":      "new\n",
+	})
+
+	exec := r.newTestGit()
+	gogit, err := NewWithBackend(GoGit)
+	if err != nil {
+		t.Fatalf("NewWithBackend(GoGit) returned error: %v", err)
+	}
+	ctx := context.Background()
+	expect, err := exec.DiffStat(ctx, r.dir, from, to)
+	if err != nil {
+		t.Fatalf("DiffStat() using the git executable returned error: %v", err)
+	}
+	got, err := gogit.DiffStat(ctx, r.dir, from, to)
+	if err != nil {
+		t.Fatalf("DiffStat() using go-git returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("DiffStat() using go-git returned:\n%+v\nthe git executable returned:\n%+v", got, expect)
+	}
+}
+
+func TestParseDescribe(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		out    string
+		expect TagDescription
+		err    string
+	}{
+		{
+			name:   "tag",
+			out:    "v1.0.0-3-g" + hashA,
+			expect: TagDescription{Tag: "v1.0.0", Commits: 3, Hash: ParseHash(hashA)},
+		}, {
+			name:   "tagged commit",
+			out:    "v1.0.0-0-g" + hashA,
+			expect: TagDescription{Tag: "v1.0.0", Commits: 0, Hash: ParseHash(hashA)},
+		}, {
+			name:   "tag with dashes",
+			out:    "v1.0.0-rc-1-3-g" + hashB,
+			expect: TagDescription{Tag: "v1.0.0-rc-1", Commits: 3, Hash: ParseHash(hashB)},
+		}, {
+			name:   "tag that looks like a description",
+			out:    "release-1-2-g" + hashA + "-12-g" + hashB,
+			expect: TagDescription{Tag: "release-1-2-g" + hashA, Commits: 12, Hash: ParseHash(hashB)},
+		}, {
+			name:   "exact match",
+			out:    "v1.0.0",
+			expect: TagDescription{Tag: "v1.0.0"},
+		}, {
+			name:   "exact match with dashes",
+			out:    "release-1.0-rc-1",
+			expect: TagDescription{Tag: "release-1.0-rc-1"},
+		}, {
+			name: "empty",
+			out:  "",
+			err:  "Unexpected git describe output",
+		}, {
+			name: "error message",
+			out:  "fatal: No names found, cannot describe anything.",
+			err:  "Unexpected git describe output",
+		}, {
+			name: "multiple lines",
+			out:  "v1.0.0-3-g" + hashA + "\nv1.0.0-3-g" + hashA,
+			err:  "Unexpected git describe output",
+		},
+	} {
+		got, err := parseDescribe(test.out)
+		switch {
+		case test.err != "":
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%v: parseDescribe() returned error '%v', expected error containing '%v'", test.name, err, test.err)
+			}
+		case err != nil:
+			t.Errorf("%v: parseDescribe() returned error: %v", test.name, err)
+		case got != test.expect:
+			t.Errorf("%v: parseDescribe() returned %+v, expected %+v", test.name, got, test.expect)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	for _, test := range []struct {
+		args   []string
+		expect string
+	}{
+		{[]string{}, ""},
+		{[]string{"fetch", "origin"}, "fetch"},
+		{[]string{"-c", "credential.helper=", "fetch", "origin"}, "fetch"},
+		{[]string{"-C", "/tmp/repo", "-c", "a=b", "push", "origin", "main"}, "push"},
+		{[]string{"--no-pager", "log", "-1"}, "log"},
+		{[]string{"-c", "a=b"}, ""},
+	} {
+		if got := command(test.args); got != test.expect {
+			t.Errorf("command(%q) returned '%v', expected '%v'", test.args, got, test.expect)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		cfg    Config
+		args   []string
+		expect time.Duration
+	}{
+		{"default", Config{}, []string{"fetch"}, gitTimeout},
+		{"configured default", Config{Timeout: time.Minute}, []string{"fetch"}, time.Minute},
+		{
+			name:   "per command",
+			cfg:    Config{Timeout: time.Minute, Timeouts: map[string]time.Duration{"fetch": time.Hour}},
+			args:   []string{"-c", "credential.helper=", "fetch", "origin"},
+			expect: time.Hour,
+		}, {
+			name:   "other command",
+			cfg:    Config{Timeout: time.Minute, Timeouts: map[string]time.Duration{"fetch": time.Hour}},
+			args:   []string{"push", "origin", "main"},
+			expect: time.Minute,
+		}, {
+			name:   "other command without default",
+			cfg:    Config{Timeouts: map[string]time.Duration{"fetch": time.Hour}},
+			args:   []string{"push", "origin", "main"},
+			expect: gitTimeout,
+		}, {
+			name:   "argument is not the command",
+			cfg:    Config{Timeouts: map[string]time.Duration{"origin": time.Hour}},
+			args:   []string{"fetch", "origin"},
+			expect: gitTimeout,
+		},
+	} {
+		g := Git{cfg: test.cfg}
+		if got := g.timeout(test.args); got != test.expect {
+			t.Errorf("%v: timeout(%q) returned %v, expected %v", test.name, test.args, got, test.expect)
+		}
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	for _, test := range []struct {
+		wd     string
+		args   []string
+		expect string
+	}{
+		{"", []string{"fetch", "origin"}, "git fetch origin"},
+		{"/tmp/repo", []string{"status"}, "(in /tmp/repo) git status"},
+		{"", []string{"commit", "-m", "Release 1.2.3"}, `git commit -m "Release 1.2.3"`},
+		{"", []string{"log", "--format=%H"}, "git log --format=%H"},
+		{"", []string{"tag", "-m", "", "v1"}, `git tag -m "" v1`},
+		{"", []string{"commit", "-m", "It's"}, `git commit -m "It's"`},
+	} {
+		if got := commandLine("git", test.wd, test.args); got != test.expect {
+			t.Errorf("commandLine('git', '%v', %q) returned '%v', expected '%v'", test.wd, test.args, got, test.expect)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	defer func(f func(time.Duration) <-chan time.Time) { after = f }(after)
+
+	errNetwork := Error{Kind: ErrNetwork, Err: errors.New("Connection reset")}
+	errAuth := Error{Kind: ErrAuthFailed, Err: errors.New("Authentication failed")}
+	for _, test := range []struct {
+		name     string
+		policy   RetryPolicy
+		errs     []error // Errors returned by each attempt. nil once exhausted
+		attempts int
+		delays   []time.Duration
+		err      error
+	}{
+		{
+			name:     "success",
+			policy:   RetryPolicy{Attempts: 3, Delay: time.Second},
+			errs:     []error{},
+			attempts: 1,
+			delays:   []time.Duration{},
+		}, {
+			name:     "succeeds after retries",
+			policy:   RetryPolicy{Attempts: 3, Delay: time.Second},
+			errs:     []error{errNetwork, errNetwork},
+			attempts: 3,
+			delays:   []time.Duration{time.Second, 2 * time.Second},
+		}, {
+			name:     "attempts exhausted",
+			policy:   RetryPolicy{Attempts: 3, Delay: time.Second},
+			errs:     []error{errNetwork, errNetwork, errNetwork, errNetwork},
+			attempts: 3,
+			delays:   []time.Duration{time.Second, 2 * time.Second},
+			err:      errNetwork,
+		}, {
+			name:     "max delay",
+			policy:   RetryPolicy{Attempts: 5, Delay: time.Second, MaxDelay: 3 * time.Second},
+			errs:     []error{errNetwork, errNetwork, errNetwork, errNetwork, errNetwork},
+			attempts: 5,
+			delays:   []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+			err:      errNetwork,
+		}, {
+			name:     "not retryable",
+			policy:   RetryPolicy{Attempts: 3, Delay: time.Second},
+			errs:     []error{errAuth, errNetwork},
+			attempts: 1,
+			delays:   []time.Duration{},
+			err:      errAuth,
+		}, {
+			name:     "not retryable after retry",
+			policy:   RetryPolicy{Attempts: 3, Delay: time.Second},
+			errs:     []error{errNetwork, errAuth, errNetwork},
+			attempts: 2,
+			delays:   []time.Duration{time.Second},
+			err:      errAuth,
+		}, {
+			name:     "single attempt",
+			policy:   RetryPolicy{Attempts: 1, Delay: time.Second},
+			errs:     []error{errNetwork},
+			attempts: 1,
+			delays:   []time.Duration{},
+			err:      errNetwork,
+		}, {
+			name:     "default policy",
+			policy:   RetryPolicy{},
+			errs:     []error{errNetwork, errNetwork, errNetwork},
+			attempts: DefaultRetryPolicy.Attempts,
+			delays:   []time.Duration{2 * time.Second, 4 * time.Second},
+			err:      errNetwork,
+		},
+	} {
+		delays := []time.Duration{}
+		after = func(d time.Duration) <-chan time.Time {
+			delays = append(delays, d)
+			c := make(chan time.Time, 1)
+			c <- time.Time{}
+			return c
+		}
+		attempts := 0
+		g := Git{cfg: Config{Retry: test.policy}}
+		err := g.retry(context.Background(), func() error {
+			attempts++
+			if attempts <= len(test.errs) {
+				return test.errs[attempts-1]
+			}
+			return nil
+		})
+		if err != test.err {
+			t.Errorf("%v: retry() returned error '%v', expected '%v'", test.name, err, test.err)
+		}
+		if attempts != test.attempts {
+			t.Errorf("%v: retry() made %v attempts, expected %v", test.name, attempts, test.attempts)
+		}
+		if !reflect.DeepEqual(delays, test.delays) {
+			t.Errorf("%v: retry() waited %v, expected %v", test.name, delays, test.delays)
+		}
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	defer func(f func(time.Duration) <-chan time.Time) { after = f }(after)
+	after = func(time.Duration) <-chan time.Time { return nil } // Never fires
+
+	errNetwork := Error{Kind: ErrNetwork, Err: errors.New("Connection reset")}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts := 0
+	g := Git{cfg: Config{Retry: RetryPolicy{Attempts: 3, Delay: time.Hour}}}
+	err := g.retry(ctx, func() error {
+		attempts++
+		return errNetwork
+	})
+	if err != errNetwork || attempts != 1 {
+		t.Errorf("retry() returned '%v' after %v attempts, expected '%v' after 1 attempt", err, attempts, errNetwork)
+	}
+}
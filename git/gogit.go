@@ -0,0 +1,714 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// goGit implements the git operations using the pure-Go go-git library.
+type goGit struct{}
+
+// pushRef is the temporary local reference used to push a commit hash with
+// go-git, which can only push references.
+const pushRef = plumbing.ReferenceName("refs/release-me/push")
+
+func (goGit) open(wd string) (*gogit.Repository, error) {
+	if wd == "" {
+		wd = "."
+	}
+	repo, err := gogit.PlainOpenWithOptions(wd, &gogit.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open git repository at '%v': %w", wd, err)
+	}
+	return repo, nil
+}
+
+func (g goGit) add(ctx context.Context, wd, file string) error {
+	repo, err := g.open(wd)
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if filepath.IsAbs(file) {
+		if file, err = filepath.Rel(wt.Filesystem.Root(), file); err != nil {
+			return err
+		}
+	}
+	_, err = wt.Add(filepath.ToSlash(file))
+	return err
+}
+
+func (g goGit) commit(ctx context.Context, wd, msg string, flags CommitFlags) error {
+	repo, err := g.open(wd)
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	opts := &gogit.CommitOptions{}
+	if flags.Name != "" || flags.Email != "" {
+		opts.Author = &object.Signature{Name: flags.Name, Email: flags.Email, When: time.Now()}
+	}
+	_, err = wt.Commit(msg, opts)
+	return err
+}
+
+func (g goGit) push(ctx context.Context, wd, remote, localBranch, remoteBranch string, flags PushFlags) error {
+	repo, err := g.open(wd)
+	if err != nil {
+		return err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(localBranch))
+	if err != nil {
+		return fmt.Errorf("Failed to resolve '%v': %w", localBranch, classifyGoGit(err))
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(pushRef, *hash)); err != nil {
+		return err
+	}
+	defer repo.Storer.RemoveReference(pushRef)
+	spec := config.RefSpec(fmt.Sprintf("%v:refs/heads/%v", pushRef, remoteBranch))
+	return g.pushRefSpecs(ctx, repo, remote, flags, spec)
+}
+
+func (g goGit) pushTags(ctx context.Context, wd, remote string, flags PushFlags) error {
+	repo, err := g.open(wd)
+	if err != nil {
+		return err
+	}
+	return g.pushRefSpecs(ctx, repo, remote, flags, "refs/tags/*:refs/tags/*")
+}
+
+func (g goGit) pushTag(ctx context.Context, wd, remote, name string, flags PushFlags) error {
+	repo, err := g.open(wd)
+	if err != nil {
+		return err
+	}
+	spec := config.RefSpec(fmt.Sprintf("refs/tags/%v:refs/tags/%v", name, name))
+	return g.pushRefSpecs(ctx, repo, remote, flags, spec)
+}
+
+func (goGit) pushRefSpecs(ctx context.Context, repo *gogit.Repository, url string, flags PushFlags, specs ...config.RefSpec) error {
+	auth, err := flags.goGitAuth(url)
+	if err != nil {
+		return err
+	}
+	if flags.Force {
+		for i, spec := range specs {
+			if !spec.IsForceUpdate() {
+				specs[i] = "+" + spec
+			}
+		}
+	}
+	remote := gogit.NewRemote(repo.Storer, &config.RemoteConfig{Name: "anonymous", URLs: []string{url}})
+	err = remote.PushContext(ctx, &gogit.PushOptions{
+		RemoteName: "anonymous",
+		RefSpecs:   specs,
+		Auth:       auth,
+	})
+	if err == gogit.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return classifyGoGit(err)
+}
+
+func (g goGit) fetchTags(ctx context.Context, wd, url string, cred Credentials) error {
+	repo, err := g.open(wd)
+	if err != nil {
+		return err
+	}
+	auth, err := cred.goGitAuth(url)
+	if err != nil {
+		return err
+	}
+	remote := gogit.NewRemote(repo.Storer, &config.RemoteConfig{Name: "anonymous", URLs: []string{url}})
+	err = remote.FetchContext(ctx, &gogit.FetchOptions{
+		RemoteName: "anonymous",
+		RefSpecs:   []config.RefSpec{"+refs/tags/*:refs/tags/*"},
+		Tags:       gogit.NoTags,
+		Auth:       auth,
+	})
+	if err == gogit.NoErrAlreadyUpToDate {
+		return nil
+	}
+	return classifyGoGit(err)
+}
+
+// goGitAuth returns the go-git authentication method used for url.
+// SSH remotes use the key file, if specified, otherwise ssh-agent.
+func (f Credentials) goGitAuth(url string) (transport.AuthMethod, error) {
+	if isSSH(url) {
+		user := "git"
+		if ep, err := transport.NewEndpoint(url); err == nil && ep.User != "" {
+			user = ep.User
+		}
+		if f.SSHKey != "" {
+			return ssh.NewPublicKeysFromFile(user, f.SSHKey, "")
+		}
+		return ssh.NewSSHAgentAuth(user)
+	}
+	if f.Username == "" {
+		return nil, nil
+	}
+	return &http.BasicAuth{Username: f.Username, Password: f.Password}, nil
+}
+
+// checkoutRemote initializes a new repository at path, fetches the specs from
+// url, and checks out the commit returned by resolve.
+// go-git does not support partial clones, so flags.Filter is ignored.
+func (goGit) checkoutRemote(ctx context.Context, path, url string, specs []config.RefSpec, flags CheckoutFlags, resolve func(*gogit.Repository) (plumbing.Hash, error)) error {
+	if err := os.MkdirAll(path, 0777); err != nil {
+		return fmt.Errorf("mkdir '%v' failed: %w", path, err)
+	}
+	err := func() error {
+		repo, err := gogit.PlainInit(path, false)
+		if err != nil {
+			return err
+		}
+		remote, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{url}})
+		if err != nil {
+			return err
+		}
+		auth, err := flags.goGitAuth(url)
+		if err != nil {
+			return err
+		}
+		opts := &gogit.FetchOptions{RefSpecs: specs, Depth: flags.Depth, Tags: gogit.NoTags, Auth: auth}
+		if err := remote.FetchContext(ctx, opts); err != nil && err != gogit.NoErrAlreadyUpToDate {
+			return fmt.Errorf("Failed to fetch from '%v': %w", url, classifyGoGit(err))
+		}
+		hash, err := resolve(repo)
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		wt, err := repo.Worktree()
+		if err != nil {
+			return err
+		}
+		return wt.Checkout(&gogit.CheckoutOptions{Hash: hash})
+	}()
+	if err != nil {
+		os.RemoveAll(path)
+	}
+	return err
+}
+
+func (g goGit) checkoutRemoteBranch(ctx context.Context, path, url string, branch string, flags CheckoutFlags) error {
+	spec := config.RefSpec(fmt.Sprintf("+refs/heads/%v:refs/remotes/origin/%v", branch, branch))
+	return g.checkoutRemote(ctx, path, url, []config.RefSpec{spec}, flags, func(repo *gogit.Repository) (plumbing.Hash, error) {
+		ref, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("Branch '%v' not found: %w", branch, classifyGoGit(err))
+		}
+		return ref.Hash(), nil
+	})
+}
+
+func (g goGit) checkoutRemoteCommit(ctx context.Context, path, url string, commit Hash, flags CheckoutFlags) error {
+	// go-git cannot fetch a commit by hash, so fetch all branches and tags.
+	// The commit may be older than the branch and tag tips, so fetch the full
+	// history.
+	flags.Depth = 0
+	specs := []config.RefSpec{
+		"+refs/heads/*:refs/remotes/origin/*",
+		"+refs/tags/*:refs/tags/*",
+	}
+	return g.checkoutRemote(ctx, path, url, specs, flags, func(*gogit.Repository) (plumbing.Hash, error) {
+		return plumbing.Hash(commit), nil
+	})
+}
+
+func (g goGit) tag(ctx context.Context, path, name string, at Hash, flags TagFlags) error {
+	repo, err := g.open(path)
+	if err != nil {
+		return err
+	}
+	if flags.Force {
+		if err := repo.DeleteTag(name); err != nil && err != gogit.ErrTagNotFound {
+			return err
+		}
+	}
+	var opts *gogit.CreateTagOptions
+	if flags.Message != "" {
+		tagger, err := g.signature(repo, flags.Name, flags.Email)
+		if err != nil {
+			return err
+		}
+		opts = &gogit.CreateTagOptions{Tagger: tagger, Message: flags.Message}
+	}
+	_, err = repo.CreateTag(name, plumbing.Hash(at), opts)
+	return err
+}
+
+// signature returns a signature using name and email, falling back to the
+// user identity in the git configuration for those that are empty.
+func (goGit) signature(repo *gogit.Repository, name, email string) (*object.Signature, error) {
+	if name == "" || email == "" {
+		cfg, err := repo.ConfigScoped(config.SystemScope)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = cfg.User.Name
+		}
+		if email == "" {
+			email = cfg.User.Email
+		}
+	}
+	if name == "" || email == "" {
+		return nil, fmt.Errorf("No git user identity configured")
+	}
+	return &object.Signature{Name: name, Email: email, When: time.Now()}, nil
+}
+
+func (g goGit) checkoutCommit(ctx context.Context, path string, commit Hash) error {
+	repo, err := g.open(path)
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return wt.Checkout(&gogit.CheckoutOptions{Hash: plumbing.Hash(commit)})
+}
+
+func (goGit) lsRemote(ctx context.Context, url string, cred Credentials) (map[string]Hash, error) {
+	auth, err := cred.goGitAuth(url)
+	if err != nil {
+		return nil, err
+	}
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+	c, err := client.NewClient(ep)
+	if err != nil {
+		return nil, err
+	}
+	s, err := c.NewUploadPackSession(ep, auth)
+	if err != nil {
+		return nil, classifyGoGit(err)
+	}
+	defer s.Close()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ar, err := s.AdvertisedReferences()
+	if err != nil {
+		return nil, classifyGoGit(err)
+	}
+	refs := map[string]Hash{}
+	for name, hash := range ar.References {
+		refs[name] = Hash(hash)
+	}
+	for name, hash := range ar.Peeled {
+		refs[name] = Hash(hash)
+	}
+	return refs, nil
+}
+
+func (g goGit) logFrom(ctx context.Context, wd, at string, count int, flags LogFlags) ([]ChangeList, error) {
+	repo, err := g.open(wd)
+	if err != nil {
+		return nil, err
+	}
+	if at == "" {
+		at = "HEAD"
+	}
+	from, exclude := at, ""
+	if i := strings.Index(at, ".."); i >= 0 {
+		exclude, from = at[:i], at[i+2:]
+		if from == "" {
+			from = "HEAD"
+		}
+	}
+
+	excluded := map[plumbing.Hash]bool{}
+	if exclude != "" {
+		hash, err := g.resolveCommit(repo, exclude)
+		if err != nil {
+			return nil, err
+		}
+		iter, err := repo.Log(&gogit.LogOptions{From: hash})
+		if err != nil {
+			return nil, err
+		}
+		if err := iter.ForEach(func(c *object.Commit) error {
+			excluded[c.Hash] = true
+			return ctx.Err()
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	hash, err := g.resolveCommit(repo, from)
+	if err != nil {
+		return nil, err
+	}
+	opts := &gogit.LogOptions{From: hash, Order: gogit.LogOrderCommitterTime}
+	paths, err := g.relPaths(repo, flags.Paths)
+	if err != nil {
+		return nil, err
+	}
+	excludes, err := g.relPaths(repo, flags.Excludes)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) > 0 || len(excludes) > 0 {
+		opts.PathFilter = func(p string) bool {
+			return (len(paths) == 0 || matchesPath(p, paths)) && !matchesPath(p, excludes)
+		}
+	}
+
+	// go-git cannot follow only the first parent, so gather the commits on the
+	// first parent chain, and skip all others.
+	firstParents := map[plumbing.Hash]bool{}
+	if flags.FirstParent {
+		c, err := repo.CommitObject(hash)
+		for err == nil && !excluded[c.Hash] {
+			firstParents[c.Hash] = true
+			if c.NumParents() == 0 {
+				break
+			}
+			c, err = c.Parent(0)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	iter, err := repo.Log(opts)
+	if err != nil {
+		return nil, err
+	}
+	cls := []ChangeList{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		switch {
+		case excluded[c.Hash],
+			flags.FirstParent && !firstParents[c.Hash],
+			flags.NoMerges && c.NumParents() > 1:
+			return nil
+		}
+		cls = append(cls, changeListFrom(c))
+		if count > 0 && len(cls) >= count {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	return cls, err
+}
+
+// relPaths returns the paths relative to the root of the repo's worktree, using
+// forward slashes. Paths of the worktree root are omitted.
+func (goGit) relPaths(repo *gogit.Repository, paths []string) ([]string, error) {
+	out := []string{}
+	for _, path := range paths {
+		if filepath.IsAbs(path) {
+			wt, err := repo.Worktree()
+			if err != nil {
+				return nil, err
+			}
+			if path, err = filepath.Rel(wt.Filesystem.Root(), path); err != nil {
+				return nil, err
+			}
+		}
+		if path = filepath.ToSlash(path); path != "." {
+			out = append(out, path)
+		}
+	}
+	return out, nil
+}
+
+// matchesPath returns true if the repo-relative file path p is one of paths,
+// or is in one of the paths directories.
+func matchesPath(p string, paths []string) bool {
+	for _, path := range paths {
+		if p == path || strings.HasPrefix(p, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (g goGit) parent(ctx context.Context, cl ChangeList) (ChangeList, error) {
+	repo, err := g.open("")
+	if err != nil {
+		return ChangeList{}, err
+	}
+	c, err := repo.CommitObject(plumbing.Hash(cl.Hash))
+	if err != nil {
+		return ChangeList{}, err
+	}
+	p, err := c.Parent(0)
+	if err != nil {
+		return ChangeList{}, err
+	}
+	return changeListFrom(p), nil
+}
+
+func (g goGit) mergeBase(ctx context.Context, wd string, a, b Hash) (Hash, error) {
+	repo, err := g.open(wd)
+	if err != nil {
+		return Hash{}, err
+	}
+	ca, err := repo.CommitObject(plumbing.Hash(a))
+	if err != nil {
+		return Hash{}, err
+	}
+	cb, err := repo.CommitObject(plumbing.Hash(b))
+	if err != nil {
+		return Hash{}, err
+	}
+	bases, err := ca.MergeBase(cb)
+	if err != nil {
+		return Hash{}, err
+	}
+	if len(bases) == 0 {
+		return Hash{}, fmt.Errorf("Failed to find merge base of %v and %v", a, b)
+	}
+	return Hash(bases[0].Hash), nil
+}
+
+func (g goGit) isAncestor(ctx context.Context, wd string, ancestor, descendant Hash) (bool, error) {
+	repo, err := g.open(wd)
+	if err != nil {
+		return false, err
+	}
+	ca, err := repo.CommitObject(plumbing.Hash(ancestor))
+	if err != nil {
+		return false, err
+	}
+	cd, err := repo.CommitObject(plumbing.Hash(descendant))
+	if err != nil {
+		return false, err
+	}
+	return ca.IsAncestor(cd)
+}
+
+func (g goGit) changedFiles(ctx context.Context, wd string, from, to Hash) ([]string, error) {
+	changes, err := g.diffTrees(wd, from, to)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, c := range changes {
+		if c.From.Name != "" {
+			files = append(files, c.From.Name)
+		}
+		if c.To.Name != "" && c.To.Name != c.From.Name {
+			files = append(files, c.To.Name)
+		}
+	}
+	return files, nil
+}
+
+func (g goGit) diffStat(ctx context.Context, wd string, from, to Hash) (DiffStat, error) {
+	changes, err := g.diffTrees(wd, from, to)
+	if err != nil {
+		return DiffStat{}, err
+	}
+	patch, err := changes.PatchContext(ctx)
+	if err != nil {
+		return DiffStat{}, err
+	}
+	stat := DiffStat{Files: []FileStat{}}
+	for _, fp := range patch.FilePatches() {
+		pf, pt := fp.Files()
+		file := FileStat{Binary: fp.IsBinary()}
+		if pt != nil {
+			file.Path = pt.Path()
+		} else {
+			file.Path = pf.Path()
+		}
+		for _, chunk := range fp.Chunks() {
+			switch chunk.Type() {
+			case fdiff.Add:
+				file.Added += countLines(chunk.Content())
+			case fdiff.Delete:
+				file.Deleted += countLines(chunk.Content())
+			}
+		}
+		stat.Files = append(stat.Files, file)
+		stat.Added += file.Added
+		stat.Deleted += file.Deleted
+	}
+	return stat, nil
+}
+
+// countLines returns the number of lines in s, including a final line that
+// has no trailing newline.
+func countLines(s string) int {
+	n := strings.Count(s, "\n")
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		n++
+	}
+	return n
+}
+
+// diffTrees returns the changes between the trees of the commits from and to.
+func (g goGit) diffTrees(wd string, from, to Hash) (object.Changes, error) {
+	repo, err := g.open(wd)
+	if err != nil {
+		return nil, err
+	}
+	trees := [2]*object.Tree{}
+	for i, h := range []Hash{from, to} {
+		c, err := repo.CommitObject(plumbing.Hash(h))
+		if err != nil {
+			return nil, classifyGoGit(err)
+		}
+		if trees[i], err = c.Tree(); err != nil {
+			return nil, err
+		}
+	}
+	return object.DiffTree(trees[0], trees[1])
+}
+
+// nearestTag finds the tagged commit reachable from ref with the most recent
+// commit time, and counts the commits reachable from ref but not the tag.
+func (g goGit) nearestTag(ctx context.Context, wd, ref string) (TagDescription, error) {
+	repo, err := g.open(wd)
+	if err != nil {
+		return TagDescription{}, err
+	}
+	hash, err := g.resolveCommit(repo, ref)
+	if err != nil {
+		return TagDescription{}, err
+	}
+
+	tagged := map[plumbing.Hash]string{}
+	tags, err := repo.Tags()
+	if err != nil {
+		return TagDescription{}, err
+	}
+	if err := tags.ForEach(func(r *plumbing.Reference) error {
+		if h, err := g.resolveCommit(repo, r.Name().String()); err == nil {
+			tagged[h] = r.Name().Short()
+		}
+		return nil
+	}); err != nil {
+		return TagDescription{}, err
+	}
+
+	iter, err := repo.Log(&gogit.LogOptions{From: hash, Order: gogit.LogOrderCommitterTime})
+	if err != nil {
+		return TagDescription{}, err
+	}
+	tag, tagHash := "", plumbing.ZeroHash
+	err = iter.ForEach(func(c *object.Commit) error {
+		if name, ok := tagged[c.Hash]; ok {
+			tag, tagHash = name, c.Hash
+			return storer.ErrStop
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return TagDescription{}, err
+	}
+	if tag == "" {
+		return TagDescription{}, Error{Kind: ErrRefNotFound, Err: fmt.Errorf("No tags can describe '%v'", ref)}
+	}
+
+	cls, err := g.logFrom(ctx, wd, fmt.Sprintf("%v..%v", tagHash, hash), 0, LogFlags{})
+	if err != nil {
+		return TagDescription{}, err
+	}
+	return TagDescription{Tag: tag, Commits: len(cls), Hash: Hash(hash)}, nil
+}
+
+func (g goGit) show(ctx context.Context, wd, path, at string) ([]byte, error) {
+	repo, err := g.open(wd)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := g.resolveCommit(repo, at)
+	if err != nil {
+		return nil, err
+	}
+	c, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	f, err := c.File(path)
+	if err != nil {
+		return nil, fmt.Errorf("'%v' not found at %v: %w", path, at, err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// resolveCommit returns the commit hash for the revision rev, peeling any
+// annotated tags.
+func (goGit) resolveCommit(repo *gogit.Repository, rev string) (plumbing.Hash, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("Failed to resolve '%v': %w", rev, classifyGoGit(err))
+	}
+	if tag, err := repo.TagObject(*hash); err == nil {
+		c, err := tag.Commit()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return c.Hash, nil
+	}
+	return *hash, nil
+}
+
+// changeListFrom returns the ChangeList for the go-git commit c.
+func changeListFrom(c *object.Commit) ChangeList {
+	msg := strings.TrimSpace(c.Message)
+	subject, description := msg, ""
+	if i := strings.Index(msg, "\n"); i >= 0 {
+		subject, description = msg[:i], msg[i+1:]
+	}
+	return ChangeList{
+		Hash:        Hash(c.Hash),
+		Date:        c.Committer.When,
+		Author:      fmt.Sprintf("%v <%v>", c.Author.Name, c.Author.Email),
+		Subject:     strings.TrimSpace(subject),
+		Description: strings.TrimSpace(description),
+	}
+}
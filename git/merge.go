@@ -0,0 +1,168 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MergeFlags advanced flags for Merge, CherryPick and Revert.
+type MergeFlags struct {
+	CommitFlags              // Identity and signing of the new commits
+	Strategy        string   // Merge strategy, e.g. 'recursive' or 'ours'. Uses git's default if empty
+	StrategyOptions []string // Options for the merge strategy, e.g. 'theirs' or 'ignore-space-change'
+	Mainline        int      // CherryPick and Revert: parent number of the merge commit to use as the mainline
+	NoFastForward   bool     // Merge: always create a merge commit
+	Message         string   // Merge: commit message. Uses git's default message if empty
+}
+
+// args returns the git arguments for the strategy and signing flags.
+func (f MergeFlags) args() []string {
+	args := []string{}
+	if f.Strategy != "" {
+		args = append(args, "--strategy="+f.Strategy)
+	}
+	for _, o := range f.StrategyOptions {
+		args = append(args, "--strategy-option="+o)
+	}
+	if f.Sign.Enabled() {
+		args = append(args, "--gpg-sign")
+	}
+	return args
+}
+
+// Conflict describes a file that could not be merged.
+type Conflict struct {
+	Path string // Repo-relative path of the file
+	Kind string // Kind of conflict, e.g. 'content' or 'modify/delete'
+}
+
+// ConflictError is the error returned by Merge, CherryPick and Revert when
+// the changes could not be applied due to conflicts. The operation is aborted
+// before the error is returned, restoring the state of the repo.
+// errors.Is(err, ErrMergeConflict) returns true for ConflictErrors.
+type ConflictError struct {
+	Op        string     // Operation that failed, e.g. 'cherry-pick'
+	Conflicts []Conflict // The conflicting files
+	Err       error      // The underlying error
+}
+
+func (e ConflictError) Error() string {
+	paths := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		paths[i] = fmt.Sprintf("%v (%v)", c.Path, c.Kind)
+	}
+	return fmt.Sprintf("git %v failed due to conflicts in: %v", e.Op, strings.Join(paths, ", "))
+}
+
+// Unwrap returns the underlying error.
+func (e ConflictError) Unwrap() error { return e.Err }
+
+// Is returns true if target is ErrMergeConflict.
+func (e ConflictError) Is(target error) bool { return target == ErrMergeConflict }
+
+// conflictKinds maps the 'git status --porcelain' codes of unmerged files to
+// the kind of conflict.
+var conflictKinds = map[string]string{
+	"UU": "content",
+	"AA": "add/add",
+	"DD": "delete/delete",
+	"AU": "added by us",
+	"UA": "added by them",
+	"DU": "delete/modify",
+	"UD": "modify/delete",
+}
+
+// CherryPick applies the changes of the commit to the current branch of the
+// repo at wd, creating a new commit.
+func (g Git) CherryPick(ctx context.Context, wd string, commit Hash, flags MergeFlags) error {
+	args := []string{}
+	if flags.Mainline > 0 {
+		args = append(args, fmt.Sprintf("--mainline=%d", flags.Mainline))
+	}
+	return g.apply(ctx, wd, "cherry-pick", flags, append(args, commit.String())...)
+}
+
+// Revert reverts the changes of the commit on the current branch of the repo
+// at wd, creating a new commit.
+func (g Git) Revert(ctx context.Context, wd string, commit Hash, flags MergeFlags) error {
+	args := []string{"--no-edit"}
+	if flags.Mainline > 0 {
+		args = append(args, fmt.Sprintf("--mainline=%d", flags.Mainline))
+	}
+	return g.apply(ctx, wd, "revert", flags, append(args, commit.String())...)
+}
+
+// Merge merges the commit into the current branch of the repo at wd.
+func (g Git) Merge(ctx context.Context, wd string, commit Hash, flags MergeFlags) error {
+	args := []string{"--no-edit"}
+	if flags.NoFastForward {
+		args = append(args, "--no-ff")
+	}
+	if flags.Message != "" {
+		args = append(args, "--message", flags.Message)
+	}
+	return g.apply(ctx, wd, "merge", flags, append(args, commit.String())...)
+}
+
+// apply runs the git command op, which is one of 'cherry-pick', 'revert' or
+// 'merge'. If the command fails due to conflicts, then the operation is
+// aborted and a ConflictError is returned.
+func (g Git) apply(ctx context.Context, wd, op string, flags MergeFlags, args ...string) error {
+	if err := g.execFallback(op); err != nil {
+		return err
+	}
+	cmd := append(flags.configArgs(), op)
+	cmd = append(cmd, flags.args()...)
+	cmd = append(cmd, args...)
+	_, err := g.run(ctx, wd, cmd...)
+	if err == nil {
+		return nil
+	}
+	conflicts, cerr := g.conflicts(ctx, wd)
+	if cerr != nil || len(conflicts) == 0 {
+		return err
+	}
+	if _, aerr := g.run(ctx, wd, op, "--abort"); aerr != nil {
+		return fmt.Errorf("Failed to abort %v: %w", op, aerr)
+	}
+	return ConflictError{Op: op, Conflicts: conflicts, Err: err}
+}
+
+// conflicts returns the unmerged files of the repo at wd.
+func (g Git) conflicts(ctx context.Context, wd string) ([]Conflict, error) {
+	out, err := g.run(ctx, wd, "status", "--porcelain", "-z")
+	if err != nil {
+		return nil, err
+	}
+	conflicts := []Conflict{}
+	entries := strings.Split(string(out), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++ // Renames and copies are followed by the original path
+			continue
+		}
+		if kind, ok := conflictKinds[entry[:2]]; ok {
+			conflicts = append(conflicts, Conflict{Path: entry[3:], Kind: kind})
+		}
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRepo is a temporary git repo used by the tests.
+type testRepo struct {
+	t   *testing.T
+	dir string
+	env []string
+}
+
+// newTestRepo creates a new git repo in a temporary directory, skipping the
+// test if the git executable is not found.
+func newTestRepo(t *testing.T) *testRepo {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git executable not found")
+	}
+	dir, err := ioutil.TempDir("", "release-me-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	r := &testRepo{t: t, dir: dir, env: []string{
+		"HOME=" + dir, // Ignore the user's git config
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	}}
+	r.git("init", "-q")
+	return r
+}
+
+// git runs the git executable in the repo, returning the trimmed output.
+func (r *testRepo) git(args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), r.env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v failed: %v\n%v", strings.Join(args, " "), err, string(out))
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit writes the files, removes the files with empty content, and commits
+// the changes, returning the hash of the new commit.
+func (r *testRepo) commit(msg string, files map[string]string) Hash {
+	for name, content := range files {
+		path := filepath.Join(r.dir, name)
+		if content == "" {
+			r.git("rm", "-q", name)
+			continue
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			r.t.Fatalf("Failed to write '%v': %v", path, err)
+		}
+		r.git("add", name)
+	}
+	r.git("commit", "-q", "-m", msg)
+	return ParseHash(r.git("rev-parse", "HEAD"))
+}
+
+// newTestGit returns a Git that uses the git executable with the repo's
+// environment.
+func (r *testRepo) newTestGit() *Git {
+	g, err := New()
+	if err != nil {
+		r.t.Fatalf("git.New() returned error: %v", err)
+	}
+	g.Configure(Config{Env: r.env})
+	return g
+}
+
+// checkAborted checks that the repo has no operation in progress, no local
+// modifications, and that HEAD is head.
+func (r *testRepo) checkAborted(head Hash) {
+	for _, ref := range []string{"MERGE_HEAD", "CHERRY_PICK_HEAD", "REVERT_HEAD"} {
+		if _, err := os.Stat(filepath.Join(r.dir, ".git", ref)); err == nil {
+			r.t.Errorf("%v exists after the operation was aborted", ref)
+		}
+	}
+	if status := r.git("status", "--porcelain"); status != "" {
+		r.t.Errorf("Repo has modifications after the operation was aborted:\n%v", status)
+	}
+	if got := ParseHash(r.git("rev-parse", "HEAD")); got != head {
+		r.t.Errorf("HEAD is %v after the operation was aborted, expected %v", got, head)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	r := newTestRepo(t)
+	defer os.RemoveAll(r.dir)
+
+	// 'UU x' is renamed by the merged branch. The original path of the rename
+	// entry looks like an unmerged file in 'git status --porcelain -z' output,
+	// so must be skipped.
+	r.commit("base", map[string]string{"a.txt": "base\n", "UU x": "unchanged\n"})
+	r.git("checkout", "-q", "-b", "other")
+	r.git("mv", "UU x", "renamed.txt")
+	other := r.commit("other", map[string]string{"a.txt": "other\n"})
+	r.git("checkout", "-q", "-")
+	head := r.commit("main", map[string]string{"a.txt": "main\n"})
+
+	err := r.newTestGit().Merge(context.Background(), r.dir, other, MergeFlags{})
+	if !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("Merge() returned error '%v', expected ErrMergeConflict", err)
+	}
+	var conflict ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Merge() returned error of type %T, expected ConflictError", err)
+	}
+	if conflict.Op != "merge" {
+		t.Errorf("ConflictError.Op was '%v', expected 'merge'", conflict.Op)
+	}
+	expect := []Conflict{{Path: "a.txt", Kind: "content"}}
+	if !reflect.DeepEqual(conflict.Conflicts, expect) {
+		t.Errorf("ConflictError.Conflicts was %+v, expected %+v", conflict.Conflicts, expect)
+	}
+	r.checkAborted(head)
+}
+
+func TestCherryPickConflict(t *testing.T) {
+	r := newTestRepo(t)
+	defer os.RemoveAll(r.dir)
+
+	r.commit("base", map[string]string{"a.txt": "base\n", "b.txt": "base\n"})
+	r.git("checkout", "-q", "-b", "other")
+	pick := r.commit("other", map[string]string{"a.txt": "other\n", "b.txt": "other\n"})
+	r.git("checkout", "-q", "-")
+	head := r.commit("main", map[string]string{"a.txt": "main\n", "b.txt": ""})
+
+	err := r.newTestGit().CherryPick(context.Background(), r.dir, pick, MergeFlags{})
+	var conflict ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("CherryPick() returned error '%v', expected ConflictError", err)
+	}
+	if conflict.Op != "cherry-pick" {
+		t.Errorf("ConflictError.Op was '%v', expected 'cherry-pick'", conflict.Op)
+	}
+	expect := []Conflict{
+		{Path: "a.txt", Kind: "content"},
+		{Path: "b.txt", Kind: "delete/modify"},
+	}
+	if !reflect.DeepEqual(conflict.Conflicts, expect) {
+		t.Errorf("ConflictError.Conflicts was %+v, expected %+v", conflict.Conflicts, expect)
+	}
+	r.checkAborted(head)
+}
+
+func TestMergeNoConflict(t *testing.T) {
+	r := newTestRepo(t)
+	defer os.RemoveAll(r.dir)
+
+	r.commit("base", map[string]string{"a.txt": "base\n"})
+	r.git("checkout", "-q", "-b", "other")
+	other := r.commit("other", map[string]string{"b.txt": "other\n"})
+	r.git("checkout", "-q", "-")
+	r.commit("main", map[string]string{"c.txt": "main\n"})
+
+	if err := r.newTestGit().Merge(context.Background(), r.dir, other, MergeFlags{}); err != nil {
+		t.Fatalf("Merge() returned error: %v", err)
+	}
+	if parents := strings.Fields(r.git("log", "-1", "--format=%P")); len(parents) != 2 {
+		t.Errorf("Merge commit has parents %v, expected 2", parents)
+	}
+}
@@ -4,6 +4,7 @@ go 1.13
 
 require (
 	github.com/gdamore/tcell v1.3.0
+	github.com/go-git/go-git/v5 v5.1.0
 	github.com/google/go-github/v32 v32.0.0
 	golang.org/x/mod v0.3.0 // indirect
 	golang.org/x/oauth2 v0.0.0-20180821212333-d2e6202438be
@@ -18,14 +18,20 @@ package main
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -61,23 +67,119 @@ func run() error {
 	repo := flag.String("repo", "", "GitHub repository name")
 	username := flag.String("user", "", "GitHub username name")
 	accesstoken := flag.String("token", "", "GitHub access token")
+	style := flag.String("style", "", "Version style of release branches and tags (e.g. 'v1.2.3' or 'release-1.2'). Detected if unspecified")
+	flavorOrder := flag.String("flavor-order", "", `Ordering of version flavors relative to the release (e.g. 'dev < beta < rc < ""')`)
+	gitBackend := flag.String("git-backend", "auto", "Git implementation to use: 'exec' (git executable), 'go-git' (pure Go), or 'auto' (exec if git is found, otherwise go-git)")
+	signKey := flag.String("sign-key", "", "GPG key ID used to sign release commits and tags")
+	signSSHKey := flag.String("sign-ssh-key", "", "Path to the SSH key used to sign release commits and tags")
+	signProgram := flag.String("sign-program", "", "Program used to sign and verify commits and tags (e.g. 'gpg2' or 'ssh-keygen')")
+	verifyTags := flag.Bool("verify-tags", false, "Verify the signatures of the existing release tags")
+	useSSH := flag.Bool("ssh", false, "Push changes using the repository's SSH URL")
+	sshKey := flag.String("ssh-key", "", "Path to the private key used to push with SSH. Uses ssh-agent if unspecified. Implies --ssh")
+	mirrors := stringList{}
+	flag.Var(&mirrors, "mirror", "Additional git remote URL that release branches and tags are pushed to. May be repeated")
+	forceMirrors := flag.Bool("force-mirrors", false, "Force-push to the --mirror remotes, overwriting branches and tags that have diverged")
+	noTUI := flag.Bool("no-tui", false, "Use a plain, line-based interface instead of the full-screen terminal UI. Used automatically when not run in a terminal, or on CI")
+	flag.BoolVar(noTUI, "plain", false, "Alias for --no-tui")
+	answersPath := flag.String("answers", "", "Path to a JSON file of answers to the prompts, used instead of asking the user. '-' reads the answers from stdin")
+	transcriptPath := flag.String("transcript", "", "Path to the file that the dialog is written to when using --answers. Defaults to stdout")
+	gitTimeout := flag.String("git-timeout", "", "Timeout of git commands, optionally per command (e.g. '30m' or '10m,fetch=1h,push=20m'). Defaults to 15m")
+	gitEnv := stringList{}
+	flag.Var(&gitEnv, "git-env", "Environment variable passed to git commands, of the form 'NAME=value' (e.g. 'GIT_TRACE=1'). May be repeated")
+	gitRetries := flag.Int("git-retries", git.DefaultRetryPolicy.Attempts, "Maximum number of attempts of git fetches and pushes that fail due to network errors")
+	gitLog := flag.String("git-log", "", "Path to a file that logs the command line of each git command, for debugging")
 	flag.Parse()
 
-	ui := ui.New()
+	backend, err := git.ParseBackend(*gitBackend)
+	if err != nil {
+		return err
+	}
+
+	gitCfg := git.Config{Env: gitEnv, Retry: git.DefaultRetryPolicy}
+	if gitCfg.Retry.Attempts = *gitRetries; gitCfg.Retry.Attempts < 1 {
+		return fmt.Errorf("--git-retries must be at least 1")
+	}
+	if gitCfg.Timeout, gitCfg.Timeouts, err = parseGitTimeouts(*gitTimeout); err != nil {
+		return err
+	}
+	for _, e := range gitEnv {
+		if !strings.Contains(e, "=") {
+			return fmt.Errorf("Invalid git environment variable '%v'. Must be of the form 'NAME=value'", e)
+		}
+	}
+	if *gitLog != "" {
+		f, err := os.Create(*gitLog)
+		if err != nil {
+			return fmt.Errorf("Failed to create git log file: %w", err)
+		}
+		defer f.Close()
+		gitCfg.Log = func(cmdline string) {
+			fmt.Fprintf(f, "%v %v\n", time.Now().Format(time.RFC3339), cmdline)
+		}
+	}
+
+	var versionStyle *semver.Style
+	if *style != "" {
+		if versionStyle = semver.ParseStyle(*style); versionStyle == nil {
+			return fmt.Errorf("Cannot parse version style '%v'", *style)
+		}
+	}
+	var order semver.FlavorOrder
+	if *flavorOrder != "" {
+		if order, err = semver.ParseFlavorOrder(*flavorOrder); err != nil {
+			return err
+		}
+	}
+
+	newUI := ui.New
+	if *noTUI {
+		newUI = ui.NewPlain
+	}
+	if *answersPath != "" {
+		answers, err := readAnswers(*answersPath)
+		if err != nil {
+			return err
+		}
+		transcript := io.Writer(os.Stdout)
+		if *transcriptPath != "" {
+			f, err := os.Create(*transcriptPath)
+			if err != nil {
+				return fmt.Errorf("Failed to create transcript file: %w", err)
+			}
+			defer f.Close()
+			transcript = f
+		}
+		newUI = func() ui.UI { return ui.NewScripted(answers, transcript) }
+	}
+	ui := newUI()
 	defer ui.Terminate()
 
-	g, err := git.New()
+	g, err := git.NewWithBackend(backend)
 	if err != nil {
-		ui.ShowMessage("git not found", errGitNotFound.Error())
+		ui.ShowMessage("git not found", "%v", errGitNotFound)
 		return errGitNotFound
 	}
+	g.Configure(gitCfg)
 
 	a := app{
 		credPath: "~/.config/release-me/credentials",
 		git:      g,
+		cache:    git.NewCache(g, cacheDir()),
 		cmdFlags: cmdFlags{
-			repoOwner: *owner,
-			repoName:  *repo,
+			repoOwner:    *owner,
+			repoName:     *repo,
+			versionStyle: versionStyle,
+			flavorOrder:  order,
+			sign: git.SignFlags{
+				KeyID:   *signKey,
+				SSHKey:  *signSSHKey,
+				Program: *signProgram,
+			},
+			verifyTags:   *verifyTags,
+			ssh:          *useSSH || *sshKey != "",
+			sshKey:       *sshKey,
+			mirrors:      mirrors,
+			forceMirrors: *forceMirrors,
 		},
 		cred: credentials{
 			Username:    *username,
@@ -94,6 +196,66 @@ func run() error {
 	return a.flowRoot(context.Background())
 }
 
+// readAnswers reads the scripted UI answers from the file at path, or from
+// stdin if path is '-'.
+func readAnswers(path string) ([]ui.Answer, error) {
+	if path == "-" {
+		return ui.ReadAnswers(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open answers file: %w", err)
+	}
+	defer f.Close()
+	return ui.ReadAnswers(f)
+}
+
+// stringList is a flag.Value that holds each occurrence of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// parseGitTimeouts parses the comma-separated list of git command timeouts s.
+// Each entry is either a duration, which is used as the default timeout, or of
+// the form 'command=duration'.
+func parseGitTimeouts(s string) (time.Duration, map[string]time.Duration, error) {
+	def, timeouts := time.Duration(0), map[string]time.Duration{}
+	if s == "" {
+		return def, timeouts, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		cmd, value := "", strings.TrimSpace(entry)
+		named := false
+		if i := strings.Index(value, "="); i >= 0 {
+			cmd, value, named = strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+1:]), true
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 || (named && cmd == "") {
+			return 0, nil, fmt.Errorf("Invalid git timeout '%v'", entry)
+		}
+		if cmd == "" {
+			def = d
+		} else {
+			timeouts[cmd] = d
+		}
+	}
+	return def, timeouts, nil
+}
+
+// cacheDir returns the directory used to hold the cached repository clones.
+func cacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "release-me", "repos")
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // app
 ////////////////////////////////////////////////////////////////////////////////
@@ -102,14 +264,23 @@ func run() error {
 type app struct {
 	cmdFlags cmdFlags
 	git      *git.Git
+	cache    *git.Cache // Cached clones used for releases
 	cred     credentials
 	credPath string
 	ui       ui.UI
 }
 
 type cmdFlags struct {
-	repoOwner string
-	repoName  string
+	repoOwner    string
+	repoName     string
+	versionStyle *semver.Style      // nil if not specified
+	flavorOrder  semver.FlavorOrder // nil if not specified
+	sign         git.SignFlags      // Signing options for release commits and tags
+	verifyTags   bool               // Verify the signatures of existing release tags
+	ssh          bool               // Push using the repository's SSH URL
+	sshKey       string             // Private key used to push with SSH. ssh-agent is used if empty
+	mirrors      []string           // Additional remotes that releases are pushed to
+	forceMirrors bool               // Force-push to the mirrors
 }
 
 // flowRoot performs the root application logic and UI flow:
@@ -138,7 +309,7 @@ func (a app) flowRoot(ctx context.Context) error {
 		)
 		tc := oauth2.NewClient(ctx, ts)
 		c = github.NewClient(tc)
-		err := a.ui.WithStatus("Fetching repositories...", func(ui.Status) error {
+		err := a.ui.WithStatus(ctx, "Fetching repositories...", func(ctx context.Context, _ ui.Status) error {
 			l, _, err := c.Repositories.List(ctx, "", &github.RepositoryListOptions{})
 			if err != nil {
 				askedForCredentials = true
@@ -149,9 +320,15 @@ func (a app) flowRoot(ctx context.Context) error {
 			for i, r := range l {
 				parts := strings.Split(r.GetFullName(), "/")
 				repos[i] = repo{
-					owner: parts[0],
-					name:  parts[1],
-					url:   r.GetCloneURL(),
+					owner:   parts[0],
+					name:    parts[1],
+					url:     r.GetCloneURL(),
+					pushURL: r.GetCloneURL(),
+					sshKey:  a.cmdFlags.sshKey,
+					private: r.GetPrivate(),
+				}
+				if a.cmdFlags.ssh {
+					repos[i].pushURL = r.GetSSHURL()
 				}
 			}
 			return nil
@@ -183,7 +360,7 @@ func (a app) flowRoot(ctx context.Context) error {
 		repos = filtered
 	}
 
-	if err := a.ui.WithStatus("Filtering repositories....", func(ui.Status) error {
+	if err := a.ui.WithStatus(ctx, "Filtering repositories....", func(ctx context.Context, _ ui.Status) error {
 		if a.cmdFlags.repoOwner != "" {
 			filterRepos(func(r repo) bool { return r.owner == a.cmdFlags.repoOwner })
 			if len(repos) == 0 {
@@ -249,7 +426,8 @@ func (a app) flowRoot(ctx context.Context) error {
 // flowRepo performs the logic and UI flow for the repo r:
 // - Retrieves the list of all branches and tags for the repo, along with
 //   CHANGES file content for each branch and tag.
-// - Determines the version style in use (1.2.3, release-1.2.3, v1.2, etc)
+// - Determines the version style in use (1.2.3, release-1.2.3, v1.2, etc),
+//   asking the user to pick a style if more than one is in use.
 // - Checks for issues with the CHANGES content, missing release branches and
 //   tags.
 // - If any tags or branches are missing, asks the user whether they should be
@@ -267,12 +445,29 @@ func (a app) flowRepo(ctx context.Context, r repo, c *github.Client) error {
 		return fmt.Errorf("Failed to fetch releases: %w", err)
 	}
 
-	r.determineVersionStyle()
+	r.flavorOrder = a.cmdFlags.flavorOrder
+	r.sign = a.cmdFlags.sign
+	r.mirrors = a.cmdFlags.mirrors
+	r.forceMirrors = a.cmdFlags.forceMirrors
+
+	styleProblems, err := a.selectVersionStyle(&r)
+	if err != nil {
+		return err
+	}
 
 	problems, err := r.validate(ctx, a.ui)
 	if err != nil {
 		return fmt.Errorf("Failed to validate changes: %w", err)
 	}
+	problems = append(problems, styleProblems...)
+
+	if a.cmdFlags.verifyTags {
+		tagProblems, err := a.verifyTagSignatures(ctx, r)
+		if err != nil {
+			return fmt.Errorf("Failed to verify tag signatures: %w", err)
+		}
+		problems = append(problems, tagProblems...)
+	}
 
 	if len(problems) > 0 {
 		ok, err := a.ui.ShowConfirmation(fmt.Sprintf("%d problems found", len(problems)), strings.Join(problems, "\n"), "Continue anyway")
@@ -312,7 +507,7 @@ func (a app) flowRepo(ctx context.Context, r repo, c *github.Client) error {
 			var numCreatedBranches, numCreatedTags, numCreatedReleases int
 			var errs []error
 			if len(r.missingBranches) > 0 || len(r.missingTags) > 0 {
-				nb, nt, e := createMissingBranchesAndTags(r, a.ui, a.git, a.cred)
+				nb, nt, e := createMissingBranchesAndTags(ctx, r, a.ui, a.git, a.cred)
 				numCreatedBranches, numCreatedTags = nb, nt
 				errs = append(errs, e...)
 
@@ -345,17 +540,20 @@ func (a app) flowRepo(ctx context.Context, r repo, c *github.Client) error {
 			if c := len(r.missingReleases); c > 0 {
 				body = append(body, fmt.Sprintf("There are still %d releases missing", c))
 			}
-			a.ui.ShowMessage(title, strings.Join(body, "\n"))
+			a.ui.ShowMessage(title, "%v", strings.Join(body, "\n"))
 			return errRestartFlow
 		}
 	}
 
 	const (
 		optCreateRelease = "New release"
+		optGenerateNotes = "Generate release notes from commits"
+		optCompare       = "Compare branch CHANGES"
+		optExportHTML    = "Export changelog as HTML"
 		optQuit          = "Quit"
 	)
 
-	options := []string{optCreateRelease, optQuit}
+	options := []string{optCreateRelease, optGenerateNotes, optCompare, optExportHTML, optQuit}
 	selection, err := a.ui.ShowMenu("Select action", options)
 	if err != nil {
 		return err
@@ -364,16 +562,62 @@ func (a app) flowRepo(ctx context.Context, r repo, c *github.Client) error {
 	switch options[selection] {
 	case optCreateRelease:
 		return a.flowReleaseMenu(ctx, r, c)
+	case optGenerateNotes:
+		return a.flowGenerateNotes(ctx, r)
+	case optCompare:
+		return a.flowCompareBranches(r)
+	case optExportHTML:
+		return a.flowExportHTML(r)
 	case optQuit:
 		return nil
 	}
 	return nil
 }
 
+// selectVersionStyle determines the style used to label release branches,
+// tags and releases of the repo r:
+// - If the --style flag was specified, then this style is used.
+// - Otherwise the style is detected from the existing branches, tags and
+//   releases. If more than one style is in use, the user is asked which style
+//   should be used.
+// - If no style can be detected, these defaults are used:
+//   branch: "release-<major>.x.x"
+//   tag:    "release-<major>.<minor>.<patch>"
+// selectVersionStyle returns any inconsistencies found with the existing
+// styles, such as versions used both with and without a patch version, even if
+// the user was asked to pick a style.
+func (a app) selectVersionStyle(r *repo) ([]string, error) {
+	if a.cmdFlags.versionStyle != nil {
+		r.versionStyle = *a.cmdFlags.versionStyle
+		return nil, nil
+	}
+	report := r.detectVersionStyles()
+	if !report.Ambiguous() {
+		if style, ok := report.Best(); ok {
+			r.versionStyle = style
+		} else {
+			r.versionStyle = semver.Style{Prefix: "release-"}
+		}
+		return report.Conflicts, nil
+	}
+	options := make([]string, len(report.Candidates))
+	for i, c := range report.Candidates {
+		options[i] = fmt.Sprintf("%v (%d uses)", c.Style.Format(semver.Version{Major: 1, Minor: 2}), c.Uses)
+	}
+	i, err := a.ui.ShowMenu("Multiple version styles are in use. Select the style for new releases (or use --style)", options)
+	if err != nil {
+		return nil, err
+	}
+	r.versionStyle = report.Candidates[i].Style
+	return report.Conflicts, nil
+}
+
 // flowReleaseMenu performs the logic and UI to create a new release for the
 // repo r:
 // - Asks the user for the main branch to release from, along with the release
-//   version.
+//   version and whether the GitHub release is a draft or prerelease.
+// - If the CHANGES file has translations, asks the user for the language of the
+//   GitHub release notes.
 // - Calls doRelease() to perform the actual release.
 func (a app) flowReleaseMenu(ctx context.Context, r repo, c *github.Client) error {
 	return a.ui.Enter("Create release", func() error {
@@ -382,19 +626,20 @@ func (a app) flowReleaseMenu(ctx context.Context, r repo, c *github.Client) erro
 		if main := r.mainBranch; main != nil {
 			mainBranchName = r.mainBranch.name
 			releaseVer = r.mainBranch.changes.CurrentVersion()
-			releaseVer.Flavor = ""
+			if r.flavorOrder == nil {
+				releaseVer.Flavor = ""
+			}
 		}
 		versionStr := releaseVer.String()
+		if main := r.mainBranch; main != nil {
+			versionStr = suggestReleaseVersion(main.changes).String()
+		}
+		draft, prerelease := "false", "false"
 		if err := a.ui.ShowForm("Create new release", []ui.TextField{
 			{
-				Name:  "Main branch",
-				Value: &mainBranchName,
-				Validate: func(s string) error {
-					if _, ok := r.branches[s]; !ok {
-						return fmt.Errorf("Unknown branch '%v'", s)
-					}
-					return nil
-				},
+				Name:    "Main branch",
+				Value:   &mainBranchName,
+				Choices: r.branchNames(),
 			}, {
 				Name:  "Version",
 				Value: &versionStr,
@@ -403,12 +648,24 @@ func (a app) flowReleaseMenu(ctx context.Context, r repo, c *github.Client) erro
 					if err != nil {
 						return err
 					}
-					if !v.GreaterEqualTo(releaseVer, false) {
+					if v.Flavor != "" {
+						return fmt.Errorf("Release version must not have a flavor (found '%v')", v.Flavor)
+					}
+					if v.Epoch != 0 {
+						return fmt.Errorf("Release version must not have an epoch, as ':' is not allowed in git tag names")
+					}
+					if r.flavorOrder != nil {
+						if r.flavorOrder.Compare(v, releaseVer) <= 0 {
+							return fmt.Errorf("Version must be greater than %v (flavor order: %v)", releaseVer, r.flavorOrder)
+						}
+					} else if !v.GreaterEqualTo(releaseVer, false) {
 						return fmt.Errorf("Version must be greater or equal to %v", releaseVer)
 					}
 					return nil
 				},
 			},
+			{Name: "Draft", Value: &draft, Checkbox: true},
+			{Name: "Prerelease", Value: &prerelease, Checkbox: true},
 		}); err != nil {
 			return err
 		}
@@ -420,35 +677,414 @@ func (a app) flowReleaseMenu(ctx context.Context, r repo, c *github.Client) erro
 		if err != nil {
 			return err
 		}
-		if err := doRelease(ctx, r, a.ui, a.git, c, b, v, a.cred); err != nil {
+		if errs := b.changes.ValidateBump(v); len(errs) > 0 {
+			problems := make([]string, len(errs))
+			for i, err := range errs {
+				problems[i] = err.Error()
+			}
+			ok, err := a.ui.ShowConfirmation("Version bump problems found", strings.Join(problems, "\n"), "Continue anyway")
+			if !ok || err != nil {
+				return err
+			}
+		}
+		flags := releaseFlags{draft: draft == "true", prerelease: prerelease == "true"}
+		if locales := b.translations.locales(); len(locales) > 0 {
+			options := []string{fmt.Sprintf("default (%v)", b.changesPath)}
+			for _, l := range locales {
+				options = append(options, fmt.Sprintf("%v (%v)", l, b.translations[l].path))
+			}
+			i, err := a.ui.ShowMenu("Language of the GitHub release notes", options)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				flags.locale = locales[i-1]
+			}
+		}
+		if err := doRelease(ctx, r, a.ui, a.git, a.cache, c, b, v, flags, a.cred); err != nil {
+			return a.recoverFromGitError("Release failed", err)
+		}
+		return nil
+	})
+}
+
+// recoverFromGitError presents an actionable description of the git error err,
+// if it is one of the typed git errors. If the error was caused by changes to
+// the remote or a network failure, then the user is asked whether the repo
+// should be re-scanned so the operation can be tried again. If the user
+// accepts, then errRestartFlow is returned, otherwise err is returned.
+func (a app) recoverFromGitError(title string, err error) error {
+	hint := gitErrorHint(err)
+	if hint == "" {
+		return err
+	}
+	if !errors.Is(err, git.ErrNonFastForward) && !errors.Is(err, git.ErrRefNotFound) && !errors.Is(err, git.ErrNetwork) {
+		a.ui.ShowMessage(title, "%v", hint)
+		return err
+	}
+	retry, uiErr := a.ui.ShowConfirmation(title, hint, "Re-scan the repository and try again?")
+	if uiErr != nil {
+		return uiErr
+	}
+	if retry {
+		return errRestartFlow
+	}
+	return err
+}
+
+// gitErrorHint returns an actionable description of the typed git error err,
+// or an empty string if err is not a typed git error.
+func gitErrorHint(err error) string {
+	switch {
+	case errors.Is(err, git.ErrAuthFailed):
+		return "Authentication with the git remote failed. " +
+			"Check the access token has the 'repo' permission, " +
+			"or that your SSH key is registered with GitHub"
+	case errors.Is(err, git.ErrNonFastForward):
+		return "The remote branch has changed since it was scanned"
+	case errors.Is(err, git.ErrRefNotFound):
+		return "A branch, tag or commit was not found. " +
+			"It may have been deleted from the remote"
+	case errors.Is(err, git.ErrNetwork):
+		return "Communication with the git remote failed after several attempts. " +
+			"Check your network connection"
+	case errors.Is(err, git.ErrMergeConflict):
+		return "The release notes could not be merged into the release branch due to conflicts. " +
+			"Resolve the conflicts manually"
+	}
+	return ""
+}
+
+// suggestReleaseVersion returns the version to suggest for the next release
+// of the CHANGES content c. This is the current version without its flavor,
+// unless the release notes document breaking changes, in which case the next
+// major version after the previous release is suggested (or the next minor
+// version for 0.x releases).
+func suggestReleaseVersion(c *changes.Content) semver.Version {
+	current := c.CurrentVersion()
+	current.Flavor = ""
+	if !c.CurrentVersionHasBreakingChanges() {
+		return current
+	}
+	kind := semver.Minor
+	if latest, ok := c.Versions().Released().Max(); ok && latest.Major > 0 {
+		kind = semver.Major
+	}
+	if next := semver.NextAfter(c.Versions(), kind); next.GreaterThan(current, false) {
+		return next
+	}
+	return current
+}
+
+// flowGenerateNotes performs the logic and UI to generate release notes for
+// the development version of the main branch of repo r:
+// - Checks out the main branch to a temporary directory.
+// - Collects the commits made since the previous release tag, generating
+//   release notes grouped by conventional commit type.
+// - Asks the user whether the generated notes should replace the current
+//   development version notes. If accepted, the CHANGES file is updated and
+//   pushed to the main branch, where it can be edited before release.
+func (a app) flowGenerateNotes(ctx context.Context, r repo) error {
+	return a.ui.Enter("Generate release notes", func() error {
+		main := r.mainBranch
+		if main == nil {
+			return fmt.Errorf("Couldn't identifiy main branch")
+		}
+		content := *main.changes
+		current := content.CurrentVersion()
+		if current.Flavor == "" {
+			return fmt.Errorf("Nothing in %v to generate notes for (top most version is not flavored)", main.changesPath)
+		}
+
+		// Find the tag of the previous release.
+		since := ""
+		for _, v := range content.Versions() {
+			if v.Flavor != "" {
+				continue
+			}
+			if t, ok := r.tags[r.tagNameForVersion(v)]; ok {
+				since = t.sha
+			}
+			break
+		}
+
+		wd := r.checkoutDir()
+		if err := os.MkdirAll(wd, 0777); err != nil {
+			return fmt.Errorf("Failed to create temporary checkout directory at '%v'", wd)
+		}
+		defer os.RemoveAll(wd)
+
+		var notes string
+		if err := a.ui.WithStatus(ctx, "Scanning commits...", func(ctx context.Context, _ ui.Status) error {
+			if err := a.git.CheckoutRemoteBranch(ctx, wd, r.url, main.name, r.checkoutFlags(a.cred)); err != nil {
+				return fmt.Errorf("Failed to checkout branch '%v': %w", main.name, err)
+			}
+			at := "HEAD"
+			if since != "" {
+				at = since + "..HEAD"
+			}
+			log, err := a.git.LogFrom(ctx, wd, at, -1, git.LogFlags{NoMerges: true})
+			if err != nil {
+				return fmt.Errorf("Failed to retrieve git log: %w", err)
+			}
+			messages := make([]string, 0, len(log))
+			for _, cl := range log {
+				if isReleaseMeCommit(cl.Subject) {
+					continue
+				}
+				messages = append(messages, cl.Subject+"\n\n"+cl.Description)
+			}
+			notes = changes.ConventionalNotes(messages)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if notes == "" {
+			a.ui.ShowMessage("No changes", "No commits found since the last release")
+			return nil
+		}
+		ok, err := a.ui.ShowConfirmation("Generated release notes for "+current.String(), notes,
+			"Replace the current release notes in "+main.changesPath+"?")
+		if !ok || err != nil {
+			return err
+		}
+
+		if err := content.SetCurrentVersionNotes(notes); err != nil {
+			return err
+		}
+		return a.ui.WithStatus(ctx, "Updating "+main.changesPath, func(ctx context.Context, _ ui.Status) error {
+			commitMsg := fmt.Sprintf("%v %v", generateNotesCommitMsg, current)
+			files := map[string]string{main.changesPath: content.String()}
+			hash, err := saveAndCommit(ctx, a.git, wd, files, commitMsg, r.sign)
+			if err != nil {
+				return err
+			}
+			pushFlags := r.pushFlags(a.cred)
+			if err := a.git.Push(ctx, wd, r.pushURL, hash.String(), main.name, pushFlags); err != nil {
+				return fmt.Errorf("Failed to push changes to main branch '%v': %w", main.name, err)
+			}
+			failures := r.pushToMirrors(a.cred, func(url string, flags git.PushFlags) error {
+				return a.git.Push(ctx, wd, url, hash.String(), main.name, flags)
+			})
+			if len(failures) > 0 {
+				a.ui.ShowMessage("Failed to push to mirrors", "%v", strings.Join(failures, "\n"))
+			}
+			return nil
+		})
+	})
+}
+
+// verifyTagSignatures verifies the signatures of the release tags of the
+// versions in the main branch's CHANGES file:
+// - Checks out the main branch of the repo r to a temporary directory.
+// - Fetches all the tags.
+// - Verifies the signature of each release tag.
+// verifyTagSignatures returns a problem for each tag that is unsigned or has
+// a signature that could not be verified.
+func (a app) verifyTagSignatures(ctx context.Context, r repo) ([]string, error) {
+	names := []string{}
+	for _, v := range r.mainBranch.changes.Versions() {
+		if name := r.tagNameForVersion(v); v.Flavor == "" && r.tags[name] != nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	wd := r.checkoutDir()
+	if err := os.MkdirAll(wd, 0777); err != nil {
+		return nil, fmt.Errorf("Failed to create temporary checkout directory at '%v'", wd)
+	}
+	defer os.RemoveAll(wd)
+
+	problems := []string{}
+	err := a.ui.WithStatus(ctx, "Verifying tag signatures...", func(ctx context.Context, s ui.Status) error {
+		if err := a.git.CheckoutRemoteBranch(ctx, wd, r.url, r.mainBranch.name, r.checkoutFlags(a.cred)); err != nil {
+			return fmt.Errorf("Failed to checkout branch '%v': %w", r.mainBranch.name, err)
+		}
+		if err := a.git.FetchTags(ctx, wd, r.url, r.gitCredentials(a.cred)); err != nil {
+			return fmt.Errorf("Failed to fetch tags: %w", err)
+		}
+		for i, name := range names {
+			s.Progress(i, len(names))
+			if err := a.git.VerifyTag(ctx, wd, name, a.cmdFlags.sign); err != nil {
+				problems = append(problems, fmt.Sprintf("Release tag '%v' does not have a valid signature", name))
+			}
+		}
+		return nil
+	})
+	return problems, err
+}
+
+// flowCompareBranches performs the logic and UI to compare the CHANGES files
+// of two branches of the repo r:
+// - Asks the user for the two branches to compare. These default to the main
+//   branch and the most recent release branch.
+// - Displays the versions found in only one of the branches, along with the
+//   versions that have differing release notes.
+func (a app) flowCompareBranches(r repo) error {
+	return a.ui.Enter("Compare", func() error {
+		nameA, nameB := "", ""
+		if r.mainBranch != nil {
+			nameA = r.mainBranch.name
+		}
+		latest := -1
+		for _, b := range r.branches {
+			if b.releaseVersion != nil && *b.releaseVersion > latest {
+				latest = *b.releaseVersion
+				nameB = b.name
+			}
+		}
+		if err := a.ui.ShowForm("Compare branch CHANGES", []ui.TextField{
+			{Name: "Branch A", Value: &nameA, Choices: r.branchNames()},
+			{Name: "Branch B", Value: &nameB, Choices: r.branchNames()},
+		}); err != nil {
+			return err
+		}
+		branchA, ok := r.branches[nameA]
+		if !ok {
+			return fmt.Errorf("Branch '%v' not found", nameA)
+		}
+		branchB, ok := r.branches[nameB]
+		if !ok {
+			return fmt.Errorf("Branch '%v' not found", nameB)
+		}
+
+		diff := changes.Compare(branchA.changes, branchB.changes)
+		if diff.Empty() {
+			a.ui.ShowMessage("No differences", "The CHANGES of '%v' and '%v' are identical", nameA, nameB)
+			return nil
+		}
+		lines := []string{}
+		for _, v := range diff.OnlyInA {
+			lines = append(lines, fmt.Sprintf("Version %v only found in '%v'", v, nameA))
+		}
+		for _, v := range diff.OnlyInB {
+			lines = append(lines, fmt.Sprintf("Version %v only found in '%v'", v, nameB))
+		}
+		for _, v := range diff.NotesDiffer {
+			lines = append(lines, fmt.Sprintf("Version %v has different release notes", v))
+		}
+		a.ui.ShowMessage(fmt.Sprintf("%d differences found", len(lines)), "%v", strings.Join(lines, "\n"))
+		return nil
+	})
+}
+
+// flowExportHTML performs the logic and UI to render the CHANGES file of a
+// branch of the repo r to a static HTML site:
+// - Asks the user for the branch and the output directory.
+// - Calls writeChangesSite() to write the HTML pages.
+func (a app) flowExportHTML(r repo) error {
+	return a.ui.Enter("Export HTML", func() error {
+		branchName := ""
+		if r.mainBranch != nil {
+			branchName = r.mainBranch.name
+		}
+		outDir := r.name + "-changes"
+		if err := a.ui.ShowForm("Export changelog as HTML", []ui.TextField{
+			{
+				Name:    "Branch",
+				Value:   &branchName,
+				Choices: r.branchNames(),
+			}, {
+				Name:  "Output directory",
+				Value: &outDir,
+			},
+		}); err != nil {
+			return err
+		}
+		b, ok := r.branches[branchName]
+		if !ok {
+			return fmt.Errorf("Branch '%v' not found", branchName)
+		}
+		title := fmt.Sprintf("%v/%v release notes", r.owner, r.name)
+		if err := writeChangesSite(outDir, title, b.changes); err != nil {
 			return err
 		}
+		a.ui.ShowMessage("Exported", "Release notes for '%v' written to '%v'", b.name, outDir)
 		return nil
 	})
 }
 
-// saveAndCommit saves the file content to path, performs a `git add`,
-// followed by `git commit` using the given commit message, returning the new
-// change's git hash.
-func saveAndCommit(g *git.Git, path string, content string, msg string) (git.Hash, error) {
-	wd := filepath.Dir(path)
+// writeChangesSite writes the CHANGES content c to the directory dir as a
+// static HTML site, suitable for publishing with GitHub Pages. The site
+// consists of an index.html page holding all versions, along with a page per
+// version.
+func writeChangesSite(dir, title string, c *changes.Content) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return fmt.Errorf("Failed to create output directory '%v': %w", dir, err)
+	}
+	write := func(name string, render func(f *os.File) error) error {
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("Failed to create '%v': %w", path, err)
+		}
+		defer f.Close()
+		if err := render(f); err != nil {
+			return fmt.Errorf("Failed to write '%v': %w", path, err)
+		}
+		return nil
+	}
+	const index = "index.html"
+	if err := write(index, func(f *os.File) error { return c.WriteHTML(f, title) }); err != nil {
+		return err
+	}
+	for _, v := range c.Versions() {
+		v := v
+		if err := write(changes.PageName(v), func(f *os.File) error {
+			return c.WriteVersionHTML(f, title, index+"#"+changes.Anchor(v), v)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subject prefixes of the commits made by release-me.
+const (
+	finalizeNotesCommitMsg = "Finalize release notes for"
+	stubNotesCommitMsg     = "Stub release notes for"
+	generateNotesCommitMsg = "Generate release notes for"
+)
 
-	// Save new CHANGES file
-	if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
-		return git.Hash{}, fmt.Errorf("Failed to save file '%v': %v", path, err)
+// isReleaseMeCommit returns true if the commit subject is of a commit made by
+// release-me.
+func isReleaseMeCommit(subject string) bool {
+	for _, prefix := range []string{finalizeNotesCommitMsg, stubNotesCommitMsg, generateNotesCommitMsg} {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
 	}
+	return false
+}
+
+// saveAndCommit saves the files to the git checkout at wd, performs a
+// `git add` for each, followed by `git commit` using the given commit message,
+// returning the new change's git hash.
+// files is a map of repo-relative file path to file content.
+// If sign is enabled, the commit is signed.
+func saveAndCommit(ctx context.Context, g *git.Git, wd string, files map[string]string, msg string, sign git.SignFlags) (git.Hash, error) {
+	for path, content := range files {
+		// Save new file
+		if err := ioutil.WriteFile(filepath.Join(wd, path), []byte(content), 0666); err != nil {
+			return git.Hash{}, fmt.Errorf("Failed to save file '%v': %v", path, err)
+		}
 
-	// git add
-	if err := g.Add(wd, path); err != nil {
-		return git.Hash{}, fmt.Errorf("Failed to stage '%v': %v", path, err)
+		// git add
+		if err := g.Add(ctx, wd, path); err != nil {
+			return git.Hash{}, fmt.Errorf("Failed to stage '%v': %v", path, err)
+		}
 	}
 
 	// git commit
-	if err := g.Commit(wd, msg, git.CommitFlags{}); err != nil {
-		return git.Hash{}, fmt.Errorf("Failed to commit changes to '%v': %v", path, err)
+	if err := g.Commit(ctx, wd, msg, git.CommitFlags{Sign: sign}); err != nil {
+		return git.Hash{}, fmt.Errorf("Failed to commit changes: %v", err)
 	}
 
-	head, err := g.HeadCL(wd)
+	head, err := g.HeadCL(ctx, wd)
 	if err != nil {
 		return git.Hash{}, fmt.Errorf("Failed to get HEAD: %v", err)
 	}
@@ -459,20 +1095,24 @@ func saveAndCommit(g *git.Git, path string, content string, msg string) (git.Has
 // createMissingBranchesAndTags checks out the repo r to a temporary directory,
 // scans the CHANGES file for all missing release branches and tags, building
 // each and pushing them to the repo r.
-func createMissingBranchesAndTags(r repo, u ui.UI, g *git.Git, cred credentials) (numCreatedBranches int, numCreatedTags int, errs []error) {
+func createMissingBranchesAndTags(ctx context.Context, r repo, u ui.UI, g *git.Git, cred credentials) (numCreatedBranches int, numCreatedTags int, errs []error) {
 	err := u.Enter("Create missing", func() error {
 		if r.mainBranch == nil {
 			return fmt.Errorf("Couldn't identifiy main branch")
 		}
 
-		wd := filepath.Join(os.TempDir(), "release-me", r.owner, r.name)
+		wd := r.checkoutDir()
 		if err := os.MkdirAll(wd, 0777); err != nil {
 			return fmt.Errorf("Failed to create temporary checkout directory at '%v'", wd)
 		}
 		defer os.RemoveAll(wd)
 
-		if err := u.WithStatus("Checking out repository...", func(ui.Status) error {
-			if err := g.CheckoutRemoteBranch(wd, r.url, r.mainBranch.name); err != nil {
+		if err := u.WithStatus(ctx, "Checking out repository...", func(ctx context.Context, _ ui.Status) error {
+			// The history scan reads the CHANGES file at every commit that
+			// changed it, so fetch the file contents up front instead of one
+			// at a time.
+			flags := git.CheckoutFlags{Credentials: r.gitCredentials(cred)}
+			if err := g.CheckoutRemoteBranch(ctx, wd, r.url, r.mainBranch.name, flags); err != nil {
 				return fmt.Errorf("Failed to checkout branch '%v': %w", r.mainBranch.name, err)
 			}
 			return nil
@@ -481,23 +1121,30 @@ func createMissingBranchesAndTags(r repo, u ui.UI, g *git.Git, cred credentials)
 		}
 
 		type versionAndHash struct {
-			v semver.Version
-			h git.Hash
+			v     semver.Version
+			h     git.Hash
+			notes string
 		}
 		branchesToCreate := []versionAndHash{}
 		tagsToCreate := []versionAndHash{}
+		createdBranches := []versionAndHash{}
+		createdTags := []versionAndHash{}
 
-		if err := u.WithStatus(fmt.Sprintf("Scanning history for '%v'...", r.mainBranch.changesPath), func(ui.Status) error {
+		if err := u.WithStatus(ctx, fmt.Sprintf("Scanning history for '%v'...", r.mainBranch.changesPath), func(ctx context.Context, s ui.Status) error {
 			missingBranches := r.missingBranches.Clone()
 			missingTags := r.missingTags.Clone()
 
-			log, err := g.Log(wd, r.mainBranch.changesPath, -1)
+			// Only follow the first parent, so that versions are associated with
+			// the commit that landed them on the main branch.
+			flags := git.LogFlags{Paths: []string{r.mainBranch.changesPath}, FirstParent: true}
+			log, err := g.LogFrom(ctx, wd, "HEAD", -1, flags)
 			if err != nil {
 				return fmt.Errorf("Failed to retrieve git log for '%v': %w", r.mainBranch.changesPath, err)
 			}
 			for i := len(log) - 1; i >= 0; i-- {
+				s.Progress(len(log)-1-i, len(log))
 				cl := log[i]
-				content, err := g.Show(wd, r.mainBranch.changesPath, cl.Hash.String())
+				content, err := g.Show(ctx, wd, r.mainBranch.changesPath, cl.Hash.String())
 				if err != nil {
 					errs = append(errs, fmt.Errorf("Failed to read '%v' at %v: %w", r.mainBranch.changesPath, cl.Hash, err))
 					continue
@@ -508,13 +1155,14 @@ func createMissingBranchesAndTags(r repo, u ui.UI, g *git.Git, cred credentials)
 					continue
 				}
 				versions := c.Versions().Set()
-				for _, v := range versions.Union(missingBranches).List() {
+				for _, v := range versions.Intersect(missingBranches).List() {
 					missingBranches.Remove(v)
-					branchesToCreate = append(branchesToCreate, versionAndHash{v, cl.Hash})
+					branchesToCreate = append(branchesToCreate, versionAndHash{v: v, h: cl.Hash})
 				}
-				for _, v := range versions.Union(missingTags).List() {
+				for _, v := range versions.Intersect(missingTags).List() {
 					missingTags.Remove(v)
-					tagsToCreate = append(tagsToCreate, versionAndHash{v, cl.Hash})
+					notes, _ := c.ReleaseNotes(v)
+					tagsToCreate = append(tagsToCreate, versionAndHash{v: v, h: cl.Hash, notes: notes})
 				}
 			}
 			return nil
@@ -522,11 +1170,13 @@ func createMissingBranchesAndTags(r repo, u ui.UI, g *git.Git, cred credentials)
 			return err
 		}
 
-		u.WithStatus(fmt.Sprintf("Creating %d missing release branches...", len(branchesToCreate)), func(ui.Status) error {
-			for _, vh := range branchesToCreate {
-				if err := createReleaseBranch(r, u, g, wd, vh.h, vh.v, cred); err == nil {
+		u.WithStatus(ctx, fmt.Sprintf("Creating %d missing release branches...", len(branchesToCreate)), func(ctx context.Context, s ui.Status) error {
+			for i, vh := range branchesToCreate {
+				s.Progress(i, len(branchesToCreate))
+				if head, err := createReleaseBranch(ctx, r, u, g, wd, vh.h, vh.v, cred); err == nil {
 					r.missingBranches.Remove(vh.v)
 					numCreatedBranches++
+					createdBranches = append(createdBranches, versionAndHash{v: vh.v, h: head})
 				} else {
 					errs = append(errs, err)
 				}
@@ -534,17 +1184,43 @@ func createMissingBranchesAndTags(r repo, u ui.UI, g *git.Git, cred credentials)
 			return nil
 		})
 
-		u.WithStatus(fmt.Sprintf("Creating %d missing release tags...", len(branchesToCreate)), func(ui.Status) error {
-			for _, vh := range tagsToCreate {
-				if err := createReleaseTag(r, u, g, wd, vh.h, vh.v, cred); err == nil {
+		u.WithStatus(ctx, fmt.Sprintf("Creating %d missing release tags...", len(tagsToCreate)), func(ctx context.Context, s ui.Status) error {
+			for i, vh := range tagsToCreate {
+				s.Progress(i, len(tagsToCreate))
+				if err := createReleaseTag(ctx, r, u, g, wd, vh.h, vh.v, vh.notes, cred); err == nil {
 					r.missingTags.Remove(vh.v)
 					numCreatedTags++
+					createdTags = append(createdTags, vh)
 				} else {
 					errs = append(errs, err)
 				}
 			}
 			return nil
 		})
+
+		// Replicate the created branches and tags to the mirrors
+		if len(r.mirrors) == 0 || len(createdBranches)+len(createdTags) == 0 {
+			return nil
+		}
+		u.WithStatus(ctx, "Pushing to mirrors...", func(ctx context.Context, _ ui.Status) error {
+			failures := r.pushToMirrors(cred, func(url string, flags git.PushFlags) error {
+				for _, vh := range createdBranches {
+					if err := g.Push(ctx, wd, url, vh.h.String(), r.branchNameForVersion(vh.v), flags); err != nil {
+						return err
+					}
+				}
+				for _, vh := range createdTags {
+					if err := g.PushTag(ctx, wd, url, r.tagNameForVersion(vh.v), flags); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			for _, f := range failures {
+				errs = append(errs, fmt.Errorf("Failed to push to mirror %v", f))
+			}
+			return nil
+		})
 		return nil
 	})
 	if err != nil {
@@ -557,7 +1233,7 @@ func createMissingBranchesAndTags(r repo, u ui.UI, g *git.Git, cred credentials)
 func createMissingReleases(ctx context.Context, r repo, u ui.UI, c *github.Client) (numCreatedReleases int, errs []error) {
 	u.Enter("Create missing releases", func() error {
 		for version := range r.missingReleases {
-			if err := createRelease(ctx, r, u, c, version); err != nil {
+			if err := createRelease(ctx, r, u, c, version, releaseFlags{}); err != nil {
 				errs = append(errs, err)
 			} else {
 				delete(r.missingReleases, version)
@@ -569,57 +1245,97 @@ func createMissingReleases(ctx context.Context, r repo, u ui.UI, c *github.Clien
 	return numCreatedReleases, errs
 }
 
+// releaseFlags holds the options of a GitHub release created by createRelease.
+type releaseFlags struct {
+	locale     string // Locale of the CHANGES translation used for the release notes
+	draft      bool   // Create the release as an unpublished draft
+	prerelease bool   // Mark the release as a prerelease
+}
+
 // createRelease creates a GitHub release for the given version for the repo r.
-func createRelease(ctx context.Context, r repo, u ui.UI, c *github.Client, version semver.Version) error {
+// The release notes are taken from the CHANGES translation for flags.locale,
+// falling back to the untranslated CHANGES file if the locale is empty or not
+// found.
+func createRelease(ctx context.Context, r repo, u ui.UI, c *github.Client, version semver.Version, flags releaseFlags) error {
 	tagName := r.tagNameForVersion(version)
 	releaseName := r.releaseNameForVersion(version)
 	tag, ok := r.tags[tagName]
 	if !ok {
 		return fmt.Errorf("Failed to find release tag '%v'", tagName)
 	}
-	releaseNotes, ok := tag.changes.ReleaseNotes(version)
+	notes := tag.changes
+	if t, ok := tag.translations[flags.locale]; ok {
+		notes = t.changes
+	}
+	releaseNotes, ok := notes.ReleaseNotes(version)
 	if !ok {
 		return fmt.Errorf("Failed to find release notes for version %v", version)
 	}
-	draft, prerelease := false, false
 	_, _, err := c.Repositories.CreateRelease(ctx, r.owner, r.name, &github.RepositoryRelease{
 		TagName:         &tagName,
 		TargetCommitish: &tag.sha,
 		Name:            &releaseName,
 		Body:            &releaseNotes,
-		Draft:           &draft,
-		Prerelease:      &prerelease})
+		Draft:           &flags.draft,
+		Prerelease:      &flags.prerelease})
 	if err != nil {
 		return fmt.Errorf("Failed to create release: %w", err)
 	}
 	return nil
 }
 
-// doRelease checks out the repo to a temporary directory, and creates or
-// updates the release branch and git tag for the release at from / v, and
-// updating the CHANGES file. The release branch, tag and updated CHANGES file
-// is pushed to the repo r.
-func doRelease(ctx context.Context, r repo, u ui.UI, g *git.Git, c *github.Client, from *branch, v semver.Version, cred credentials) error {
+// doRelease checks out the repo to a temporary worktree of the cached clone,
+// and creates or updates the release branch and git tag for the release at
+// from / v, and updating the CHANGES file. The release branch, tag and updated
+// CHANGES file is pushed to the repo r. The user is first given the chance to
+// review and edit the release notes.
+func doRelease(ctx context.Context, r repo, u ui.UI, g *git.Git, cache *git.Cache, c *github.Client, from *branch, v semver.Version, flags releaseFlags, cred credentials) error {
 	changes := *from.changes
 
+	// Translations of the CHANGES file that are updated along with changes.
+	// Translations that do not share the same current version are left as-is.
+	translations := map[string]*translation{}
+	for _, l := range from.translations.locales() {
+		t := *from.translations[l]
+		if t.changes.CurrentVersion() == changes.CurrentVersion() {
+			content := *t.changes
+			t.changes = &content
+			translations[l] = &t
+		}
+	}
+	changesFiles := func() map[string]string {
+		files := map[string]string{from.changesPath: changes.String()}
+		for _, t := range translations {
+			files[t.path] = t.changes.String()
+		}
+		return files
+	}
+
 	// Sanity checks (should be caught by validation)
 	flavor := changes.CurrentVersion().Flavor
 	if flavor == "" {
 		return fmt.Errorf("Nothing in %v to release (top most version is not flavored)", from.changesPath)
 	}
+	if v.Flavor != "" {
+		return fmt.Errorf("Release version %v must not have a flavor", v)
+	}
+	if v.Epoch != 0 {
+		return fmt.Errorf("Release version %v must not have an epoch", v)
+	}
 
-	if err := u.WithStatus("Checking out repository...", func(s ui.Status) error {
-		wd := filepath.Join(os.TempDir(), "release-me", r.owner, r.name)
+	if err := u.WithStatus(ctx, "Checking out repository...", func(ctx context.Context, s ui.Status) error {
+		wd := r.checkoutDir()
+		os.RemoveAll(wd) // The worktree directory must be empty
 		if err := os.MkdirAll(wd, 0777); err != nil {
 			return fmt.Errorf("Failed to create temporary checkout directory at '%v'", wd)
 		}
 		defer os.RemoveAll(wd)
 
-		if err := g.CheckoutRemoteBranch(wd, r.url, from.name); err != nil {
+		if err := cache.Checkout(ctx, r.url, from.name, wd, r.gitCredentials(cred)); err != nil {
 			return fmt.Errorf("Failed to checkout branch '%v': %w", from.name, err)
 		}
 
-		head, err := g.HeadCL(wd)
+		head, err := g.HeadCL(ctx, wd)
 		if err != nil {
 			return fmt.Errorf("Failed to obtain branch HEAD: %w", err)
 		}
@@ -628,57 +1344,127 @@ func doRelease(ctx context.Context, r repo, u ui.UI, g *git.Git, c *github.Clien
 			return fmt.Errorf("New changes have landed in branch '%v'. Cannot continue", from.name)
 		}
 
+		prev, prevHash, hasPrev := r.previousRelease(&changes, v)
+		if hasPrev {
+			changed, err := g.ChangedFiles(ctx, wd, prevHash, head.Hash)
+			if err != nil {
+				return fmt.Errorf("Failed to find files changed since release %v: %w", prev, err)
+			}
+			touched := false
+			for _, f := range changed {
+				touched = touched || f == from.changesPath
+			}
+			if !touched {
+				ok, err := u.ShowConfirmation("Release problems found",
+					fmt.Sprintf("%v has not been modified since release %v", from.changesPath, prev),
+					"Continue anyway")
+				if !ok || err != nil {
+					return err
+				}
+			}
+		}
+
+		// Let the user review and edit the release notes before they are
+		// committed and published.
+		original := strings.Trim(changes.CurrentVersionNotes(), "\n")
+		edited := original
+		if err := u.EditText(fmt.Sprintf("Release notes for %v", v), &edited); err != nil {
+			return err
+		}
+		if edited = strings.Trim(edited, "\n"); edited != original {
+			if err := changes.SetCurrentVersionNotes(edited); err != nil {
+				return fmt.Errorf("Failed to update the release notes: %w", err)
+			}
+		}
+
 		s.Update("Updating %v", from.changesPath)
 
 		// Rename flavored version to release version
-		v.Flavor = ""
-		changes.AdjustCurrentVersion(v, time.Now())
+		now := time.Now()
+		changes.AdjustCurrentVersion(v, now)
+		for _, t := range translations {
+			t.changes.AdjustCurrentVersion(v, now)
+		}
 
 		// Save new CHANGES file
-		changesPath := filepath.Join(wd, from.changesPath)
-		commitMsg := fmt.Sprintf("Finalize release notes for %v\n\n", v)
-		if notes := changes.CurrentVersionNotes(); notes != "" {
+		notes := changes.CurrentVersionNotes()
+		commitMsg := fmt.Sprintf("%v %v\n\n", finalizeNotesCommitMsg, v)
+		if notes != "" {
 			commitMsg += "Release Notes:\n\n"
-			commitMsg += changes.CurrentVersionNotes()
+			commitMsg += notes
 		}
-		releaseHash, err := saveAndCommit(g, changesPath, changes.String(), commitMsg)
+		releaseHash, err := saveAndCommit(ctx, g, wd, changesFiles(), commitMsg, r.sign)
 		if err != nil {
 			return err
 		}
 
 		// Create release branch, tag and GitHub release.
-		if err := createReleaseBranch(r, u, g, wd, releaseHash, v, cred); err != nil {
+		branchHead, err := createReleaseBranch(ctx, r, u, g, wd, releaseHash, v, cred)
+		if err != nil {
 			return err
 		}
-		if err := createReleaseTag(r, u, g, wd, releaseHash, v, cred); err != nil {
+		contained, err := g.IsAncestor(ctx, wd, releaseHash, branchHead)
+		if err != nil {
+			return fmt.Errorf("Failed to check release branch contains %v: %w", releaseHash, err)
+		}
+		if !contained {
+			return fmt.Errorf("Release branch '%v' does not contain the release commit %v",
+				r.branchNameForVersion(v), releaseHash)
+		}
+		if err := createReleaseTag(ctx, r, u, g, wd, releaseHash, v, notes, cred); err != nil {
 			return err
 		}
 		if err := r.fetchTags(ctx, u, c); err != nil { // Re-scan tags to reflect updates. Needed by createRelease()
 			return fmt.Errorf("Failed to fetch tags: %w", err)
 		}
-		if err := createRelease(ctx, r, u, c, v); err != nil {
+		if err := createRelease(ctx, r, u, c, v, flags); err != nil {
 			return err
 		}
 
 		// Stub main's CHANGES with a new flavored version
-		nextVer := v
+		nextVer := v.BumpPatch()
 		nextVer.Flavor = flavor
-		nextVer.Patch++
 		changes.AddNewVersion(nextVer, time.Time{}, "\n[Add release notes here]\n")
+		for _, t := range translations {
+			t.changes.AddNewVersion(nextVer, time.Time{}, "\n[Add release notes here]\n")
+		}
 
-		commitMsg = fmt.Sprintf("Stub release notes for %v\n\n", v)
-		mainHash, err := saveAndCommit(g, changesPath, changes.String(), commitMsg)
+		commitMsg = fmt.Sprintf("%v %v\n\n", stubNotesCommitMsg, v)
+		mainHash, err := saveAndCommit(ctx, g, wd, changesFiles(), commitMsg, r.sign)
 		if err != nil {
 			return err
 		}
 
 		// Push new CHANGES
-		pushFlags := git.PushFlags{Username: cred.Username, Password: cred.AccessToken}
-		if err := g.Push(wd, r.url, mainHash.String(), from.name, pushFlags); err != nil {
+		pushFlags := r.pushFlags(cred)
+		if err := g.Push(ctx, wd, r.pushURL, mainHash.String(), from.name, pushFlags); err != nil {
 			return fmt.Errorf("Failed to push changes to main branch '%v': %w", from.name, err)
 		}
 
-		u.ShowMessage("Released", "Release %v successfully made", v)
+		// Replicate the release branch, tag and main branch to the mirrors
+		if len(r.mirrors) > 0 {
+			s.Update("Pushing to mirrors...")
+		}
+		mirrorFailures := r.pushToMirrors(cred, func(url string, flags git.PushFlags) error {
+			if err := g.Push(ctx, wd, url, branchHead.String(), r.branchNameForVersion(v), flags); err != nil {
+				return err
+			}
+			if err := g.PushTag(ctx, wd, url, r.tagNameForVersion(v), flags); err != nil {
+				return err
+			}
+			return g.Push(ctx, wd, url, mainHash.String(), from.name, flags)
+		})
+
+		summary := ""
+		if hasPrev {
+			if stat, err := g.DiffStat(ctx, wd, prevHash, releaseHash); err == nil {
+				summary = fmt.Sprintf("\n%v since release %v", stat, prev)
+			}
+		}
+		if len(mirrorFailures) > 0 {
+			summary += "\n\nFailed to push to mirrors:\n" + strings.Join(mirrorFailures, "\n")
+		}
+		u.ShowMessage("Released", "Release %v successfully made%v", v, summary)
 
 		return nil
 	}); err != nil {
@@ -688,36 +1474,61 @@ func doRelease(ctx context.Context, r repo, u ui.UI, g *git.Git, c *github.Clien
 }
 
 // createReleaseBranch creates or updates an existing release branch with the
-// changes at from / v, pushing the changes to the repo r.
-// wd is the path to the local git checkout of the repo.
-func createReleaseBranch(r repo, u ui.UI, g *git.Git, wd string, from git.Hash, v semver.Version, cred credentials) error {
+// changes at from / v, pushing the changes to the repo r. createReleaseBranch
+// returns the hash of the new head of the release branch.
+// wd is the path to the local git checkout of the repo. As wd may be a
+// worktree of a cached clone, the release branch is fetched without a partial
+// clone filter.
+func createReleaseBranch(ctx context.Context, r repo, u ui.UI, g *git.Git, wd string, from git.Hash, v semver.Version, cred credentials) (git.Hash, error) {
 	releaseBranchName := r.branchNameForVersion(v)
-	pushFlags := git.PushFlags{Username: cred.Username, Password: cred.AccessToken}
+	pushFlags := r.pushFlags(cred)
 
+	head := from
 	var err error
 	if _, ok := r.branches[releaseBranchName]; ok {
-		err = u.WithStatus(fmt.Sprintf("Updating existing release branch '%v'...", releaseBranchName), func(s ui.Status) error {
+		err = u.WithStatus(ctx, fmt.Sprintf("Updating existing release branch '%v'...", releaseBranchName), func(ctx context.Context, s ui.Status) error {
 			// Checkout the target branch
-			if err := g.CheckoutRemoteBranch(wd, r.url, releaseBranchName); err != nil {
+			flags := git.CheckoutFlags{Credentials: r.gitCredentials(cred)}
+			if err := g.CheckoutRemoteBranch(ctx, wd, r.url, releaseBranchName, flags); err != nil {
 				return fmt.Errorf("Failed to checkout branch '%v': %w", releaseBranchName, err)
 			}
-			// Rebase new changes
-			if err := g.Rebase(wd, from); err != nil {
-				return fmt.Errorf("Failed to rebase branch '%v': %w", releaseBranchName, err)
-			}
-			head, err := g.HeadCL(wd)
+			branchHead, err := g.HeadCL(ctx, wd)
 			if err != nil {
 				return fmt.Errorf("Failed to get HEAD: %v", err)
 			}
-			if err := g.Push(wd, r.url, head.Hash.String(), releaseBranchName, pushFlags); err != nil {
+			// If the release branch has no changes of its own, then it can
+			// simply be fast-forwarded. Otherwise rebase its changes.
+			fastForward, err := g.IsAncestor(ctx, wd, branchHead.Hash, from)
+			if err != nil {
+				return fmt.Errorf("Failed to compare branch '%v' with %v: %w", releaseBranchName, from, err)
+			}
+			if fastForward {
+				if err := g.CheckoutCommit(ctx, wd, from); err != nil {
+					return fmt.Errorf("Failed to checkout %v: %w", from, err)
+				}
+			} else {
+				base, err := g.MergeBase(ctx, wd, branchHead.Hash, from)
+				if err != nil {
+					return fmt.Errorf("Branch '%v' has no history in common with %v: %w", releaseBranchName, from, err)
+				}
+				if err := g.Rebase(ctx, wd, from); err != nil {
+					return fmt.Errorf("Failed to rebase branch '%v', which diverged at %v: %w", releaseBranchName, base, err)
+				}
+				rebased, err := g.HeadCL(ctx, wd)
+				if err != nil {
+					return fmt.Errorf("Failed to get HEAD: %v", err)
+				}
+				head = rebased.Hash
+			}
+			if err := g.Push(ctx, wd, r.pushURL, head.String(), releaseBranchName, pushFlags); err != nil {
 				return fmt.Errorf("Failed to push changes to release branch '%v': %w", releaseBranchName, err)
 			}
 			return nil
 		})
 	} else {
-		err = u.WithStatus(fmt.Sprintf("Creating new release branch '%v'...", releaseBranchName), func(s ui.Status) error {
+		err = u.WithStatus(ctx, fmt.Sprintf("Creating new release branch '%v'...", releaseBranchName), func(ctx context.Context, s ui.Status) error {
 			// Create a new branch
-			if err := g.Push(wd, r.url, from.String(), releaseBranchName, pushFlags); err != nil {
+			if err := g.Push(ctx, wd, r.pushURL, from.String(), releaseBranchName, pushFlags); err != nil {
 				return fmt.Errorf("Failed to push changes to release branch '%v': %w", releaseBranchName, err)
 			}
 			return nil
@@ -725,23 +1536,28 @@ func createReleaseBranch(r repo, u ui.UI, g *git.Git, wd string, from git.Hash,
 	}
 
 	if err != nil {
-		return fmt.Errorf("Failed to create release branch '%v': %w", releaseBranchName, err)
+		return git.Hash{}, fmt.Errorf("Failed to create release branch '%v': %w", releaseBranchName, err)
 	}
-	return nil
+	return head, nil
 }
 
-// createReleaseTag creates a new git tag for the release at from / v, pushing
-// the changes to the repo r.
+// createReleaseTag creates a new annotated git tag for the release at from / v,
+// pushing the changes to the repo r. notes are the release notes for v, which
+// are used for the tag message.
 // wd is the path to the local git checkout of the repo.
-func createReleaseTag(r repo, u ui.UI, g *git.Git, wd string, from git.Hash, v semver.Version, cred credentials) error {
+func createReleaseTag(ctx context.Context, r repo, u ui.UI, g *git.Git, wd string, from git.Hash, v semver.Version, notes string, cred credentials) error {
 	releaseTagName := r.tagNameForVersion(v)
-	err := u.WithStatus(fmt.Sprintf("Creating release tag '%v'...", releaseTagName), func(s ui.Status) error {
-		if err := g.Tag(wd, r.tagNameForVersion(v), from); err != nil {
+	err := u.WithStatus(ctx, fmt.Sprintf("Creating release tag '%v'...", releaseTagName), func(ctx context.Context, s ui.Status) error {
+		msg := fmt.Sprintf("Release %v", v)
+		if notes = strings.TrimSpace(notes); notes != "" {
+			msg += "\n\n" + notes
+		}
+		if err := g.Tag(ctx, wd, releaseTagName, from, git.TagFlags{Message: msg, Sign: r.sign}); err != nil {
 			return fmt.Errorf("Failed to create branch tag '%v': %w", v.String(), err)
 		}
-		pushFlags := git.PushFlags{Username: cred.Username, Password: cred.AccessToken}
-		if err := g.PushTags(wd, r.url, pushFlags); err != nil {
-			return fmt.Errorf("Failed to push tags: %w", err)
+		pushFlags := r.pushFlags(cred)
+		if err := g.PushTag(ctx, wd, r.pushURL, releaseTagName, pushFlags); err != nil {
+			return fmt.Errorf("Failed to push tag '%v': %w", releaseTagName, err)
 		}
 		return nil
 	})
@@ -758,8 +1574,16 @@ func createReleaseTag(r repo, u ui.UI, g *git.Git, wd string, from git.Hash, v s
 // credentials holds a username and access token used for performing
 // authenticated GitHub operations.
 type credentials struct {
+	Username    string                       `json:"user"`
+	AccessToken string                       `json:"token"`
+	Mirrors     map[string]mirrorCredentials `json:"mirrors,omitempty"` // Credentials for mirror remotes, by URL
+}
+
+// mirrorCredentials holds the credentials used to push to a mirror remote.
+type mirrorCredentials struct {
 	Username    string `json:"user"`
 	AccessToken string `json:"token"`
+	SSHKey      string `json:"ssh-key"` // Uses ssh-agent if empty
 }
 
 // load loads the credentials in JSON format from the given file path.
@@ -796,7 +1620,7 @@ func (c credentials) save(path string) error {
 func (c *credentials) getFromUser(u ui.UI, title string) error {
 	return u.ShowForm(title, []ui.TextField{
 		{Name: "user", Value: &c.Username},
-		{Name: "access token", Value: &c.AccessToken},
+		{Name: "access token", Value: &c.AccessToken, Secret: true},
 	})
 }
 
@@ -821,8 +1645,15 @@ type repo struct {
 	owner           string              // www.github.com/<owner>/<name>
 	name            string              // www.github.com/<owner>/<name>
 	url             string              // Git remote URL
+	pushURL         string              // Git remote URL used for pushing changes
+	sshKey          string              // Private key used to push to SSH remotes, or empty to use ssh-agent
+	private         bool                // True if the repository is private
 	mainBranch      *branch             // Pointer to the default git branch
-	versionStyle    semver.Style        // Style determined from existing branch / tags names
+	versionStyle    semver.Style        // Style determined from existing branch / tags names, or --style
+	flavorOrder     semver.FlavorOrder  // Flavor ordering policy, or nil for the default
+	sign            git.SignFlags       // Signing options for release commits and tags
+	mirrors         []string            // Additional remotes that releases are pushed to
+	forceMirrors    bool                // Force-push to the mirrors
 	branches        map[string]*branch  // Existing branches by name
 	tags            map[string]*tag     // Existing tags by name
 	releases        map[string]*release // Existing releases by name
@@ -837,13 +1668,34 @@ type branch struct {
 	releaseVersion *int             // Parsed major version (nil if not a release branch)
 	changes        *changes.Content // Content of CHANGES file at sha
 	changesPath    string           // Repo-relative path to CHANGES file
+	translations   translations     // Translated CHANGES files at sha
 	problems       []error          // Problems found
 }
 
 type tag struct {
-	name    string           // Tag name
-	sha     string           // Tag git hash
-	changes *changes.Content // Content of CHANGES file at sha
+	name         string           // Tag name
+	sha          string           // Tag git hash
+	changes      *changes.Content // Content of CHANGES file at sha
+	translations translations     // Translated CHANGES files at sha
+}
+
+// translation holds a translated CHANGES file (e.g. CHANGES.zh.md).
+type translation struct {
+	path    string           // Repo-relative path to the CHANGES file
+	changes *changes.Content // Content of the CHANGES file
+}
+
+// translations is a map of locale to translated CHANGES file.
+type translations map[string]*translation
+
+// locales returns the sorted list of locales of the translations.
+func (t translations) locales() []string {
+	out := make([]string, 0, len(t))
+	for l := range t {
+		out = append(out, l)
+	}
+	sort.Strings(out)
+	return out
 }
 
 type release struct {
@@ -854,7 +1706,7 @@ type release struct {
 // fetchBranches retrieves all the branches of the repo r, populating the
 // r.branches, r.mainBranch fields.
 func (r *repo) fetchBranches(ctx context.Context, u ui.UI, c *github.Client) error {
-	return u.WithStatus("Fetching branches", func(ui.Status) error {
+	return u.WithStatus(ctx, "Fetching branches", func(ctx context.Context, s ui.Status) error {
 		repo, _, err := c.Repositories.Get(ctx, r.owner, r.name)
 		if err != nil {
 			return fmt.Errorf("Failed to fetch info for repository: %w", err)
@@ -867,7 +1719,8 @@ func (r *repo) fetchBranches(ctx context.Context, u ui.UI, c *github.Client) err
 
 		r.branches = map[string]*branch{}
 
-		for _, b := range branches {
+		for i, b := range branches {
+			s.Progress(i, len(branches))
 			b := &branch{
 				name: b.GetName(),
 				sha:  b.GetCommit().GetSHA(),
@@ -877,7 +1730,7 @@ func (r *repo) fetchBranches(ctx context.Context, u ui.UI, c *github.Client) err
 				r.mainBranch = b
 			}
 			b.releaseVersion = parseReleaseBranch(b.name)
-			b.changes, b.changesPath, err = r.fetchChanges(ctx, c, u, b.name, b.sha)
+			b.changes, b.changesPath, b.translations, b.problems, err = r.fetchChanges(ctx, c, u, b.name, b.sha, readAllChanges)
 			switch err {
 			case nil:
 				r.branches[b.name] = b
@@ -895,20 +1748,21 @@ func (r *repo) fetchBranches(ctx context.Context, u ui.UI, c *github.Client) err
 // fetchTags retrieves all the branches of the repo r, populating the r.tags
 // field.
 func (r *repo) fetchTags(ctx context.Context, u ui.UI, c *github.Client) error {
-	return u.WithStatus("Fetching tags", func(ui.Status) error {
+	return u.WithStatus(ctx, "Fetching tags", func(ctx context.Context, s ui.Status) error {
 		tags, _, err := c.Repositories.ListTags(ctx, r.owner, r.name, nil)
 		if err != nil {
 			return fmt.Errorf("Failed to list tags for repository: %w", err)
 		}
 
 		r.tags = map[string]*tag{}
-		for _, t := range tags {
+		for i, t := range tags {
+			s.Progress(i, len(tags))
 			t := &tag{
 				name: t.GetName(),
 				sha:  t.GetCommit().GetSHA(),
 			}
 
-			t.changes, _, err = r.fetchChanges(ctx, c, u, t.name, t.sha)
+			t.changes, _, t.translations, _, err = r.fetchChanges(ctx, c, u, t.name, t.sha, readTagChanges(t.name))
 
 			switch err {
 			case nil:
@@ -927,7 +1781,7 @@ func (r *repo) fetchTags(ctx context.Context, u ui.UI, c *github.Client) error {
 // fetchTags retrieves all GitHub releases of the repo r, populating the
 // r.releases field.
 func (r *repo) fetchReleases(ctx context.Context, u ui.UI, c *github.Client) error {
-	return u.WithStatus("Fetching releases", func(ui.Status) error {
+	return u.WithStatus(ctx, "Fetching releases", func(ctx context.Context, _ ui.Status) error {
 		releases, _, err := c.Repositories.ListReleases(ctx, r.owner, r.name, nil)
 		if err != nil {
 			return fmt.Errorf("Failed to list tags for repository: %w", err)
@@ -946,50 +1800,33 @@ func (r *repo) fetchReleases(ctx context.Context, u ui.UI, c *github.Client) err
 	})
 }
 
-// determineVersionStyle attempts to determine the style used to label release
-// branches, tags and releases. If no style can be determined, these defaults
-// are used:
-//   branch: "release-<major>.x.x"
-//   tag:    "release-<major>.<minor>.<patch>"
-func (r *repo) determineVersionStyle() {
-	prefixUses := map[string]int{}
-	usesPatch := true
+// detectVersionStyles returns the styles used to label the release branches,
+// tags and releases of the repo.
+func (r *repo) detectVersionStyles() semver.StyleReport {
+	names := []string{}
 	for _, b := range r.branches {
-		if s := semver.ParseStyle(b.name); s != nil {
-			prefixUses[s.Prefix] = prefixUses[s.Prefix] + 1
-			usesPatch = !s.OmitPatch && usesPatch
-		}
+		names = append(names, b.name)
 	}
 	for _, t := range r.tags {
-		if s := semver.ParseStyle(t.name); s != nil {
-			prefixUses[s.Prefix] = prefixUses[s.Prefix] + 1
-			usesPatch = !s.OmitPatch && usesPatch
-		}
+		names = append(names, t.name)
 	}
 	for _, r := range r.releases {
-		if s := semver.ParseStyle(r.name); s != nil {
-			prefixUses[s.Prefix] = prefixUses[s.Prefix] + 1
-			usesPatch = !s.OmitPatch && usesPatch
-		}
-	}
-	mostCommonPrefix := "release-"
-	mostCommonPrefixUses := 0
-	for p, c := range prefixUses {
-		if c >= mostCommonPrefixUses {
-			mostCommonPrefix = p
-			mostCommonPrefixUses = c
-		}
+		names = append(names, r.name)
 	}
-	r.versionStyle.Prefix = mostCommonPrefix
-	r.versionStyle.OmitPatch = !usesPatch
+	return semver.DetectStyle(names)
 }
 
 // fetchChanges uses the GitHub git API to obtain the CHANGES file content for
-// the given sha.
-func (r *repo) fetchChanges(ctx context.Context, c *github.Client, u ui.UI, name, sha string) (*changes.Content, string, error) {
+// the given sha, along with any translations of the CHANGES file.
+// read is used to parse the CHANGES content as it is streamed from GitHub.
+// Translations that fail to load are omitted, and returned as problems instead
+// of failing the whole fetch.
+func (r *repo) fetchChanges(ctx context.Context, c *github.Client, u ui.UI, name, sha string, read changesReader) (*changes.Content, string, translations, []error, error) {
 	var out *changes.Content
 	var changesPath string
-	err := u.WithStatus(fmt.Sprintf("Fetching changes for '%v'", name), func(ui.Status) error {
+	var localized translations
+	var problems []error
+	err := u.WithStatus(ctx, fmt.Sprintf("Fetching changes for '%v'", name), func(ctx context.Context, _ ui.Status) error {
 		commit, _, err := c.Git.GetCommit(ctx, r.owner, r.name, sha)
 		if err != nil {
 			return fmt.Errorf("Failed to fetch commit %v: %w", name, err)
@@ -998,31 +1835,98 @@ func (r *repo) fetchChanges(ctx context.Context, c *github.Client, u ui.UI, name
 		if err != nil {
 			return fmt.Errorf("Failed to fetch commit %v tree: %w", name, err)
 		}
-		changesSHA := ""
-		for _, entry := range tree.Entries {
-			if entry.GetType() == "blob" && isChangesFile(entry.GetPath()) {
-				changesSHA = entry.GetSHA()
-				changesPath = entry.GetPath()
-				break
+		fetch := func(path, sha string) (*changes.Content, error) {
+			blob := r.openBlob(ctx, c, sha)
+			defer blob.Close()
+			content, err := read(blob)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to fetch %v content for %v: %w", path, name, err)
 			}
+			return content, nil
 		}
-		if changesSHA == "" {
-			return errNoChangesFile
-		}
-		blob, _, err := c.Git.GetBlobRaw(ctx, r.owner, r.name, changesSHA)
-		if err != nil {
-			return fmt.Errorf("Failed to fetch CHANGES content for %v: %w", name, err)
+		out, changesPath, localized, problems, err = readChangesTree(tree.Entries, fetch)
+		return err
+	})
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+	return out, changesPath, localized, problems, nil
+}
+
+// readChangesTree uses fetch to read the CHANGES file and its translations
+// from the entries of a git tree, returning the CHANGES content and path, and
+// the translations. Translations that fail to load are omitted, and returned as
+// problems.
+func readChangesTree(entries []*github.TreeEntry, fetch func(path, sha string) (*changes.Content, error)) (*changes.Content, string, translations, []error, error) {
+	var out *changes.Content
+	var changesPath string
+	localized := translations{}
+	problems := []error{}
+	for _, entry := range entries {
+		if entry.GetType() != "blob" || !isChangesFile(entry.GetPath()) {
+			continue
+		}
+		switch locale := changesLocale(entry.GetPath()); {
+		case locale == "" && out == nil:
+			content, err := fetch(entry.GetPath(), entry.GetSHA())
+			if err != nil {
+				return nil, "", nil, nil, err
+			}
+			out, changesPath = content, entry.GetPath()
+		case locale != "":
+			content, err := fetch(entry.GetPath(), entry.GetSHA())
+			if err != nil {
+				problems = append(problems, err)
+				continue
+			}
+			localized[locale] = &translation{path: entry.GetPath(), changes: content}
 		}
-		out, err = changes.Read(string(blob))
-		if err != nil {
-			return fmt.Errorf("Failed to parse CHANGES content for %v: %w", name, err)
+	}
+	if out == nil {
+		return nil, "", nil, nil, errNoChangesFile
+	}
+	return out, changesPath, localized, problems, nil
+}
+
+// changesReader is the signature of a function that parses CHANGES content from
+// a reader.
+type changesReader func(io.Reader) (*changes.Content, error)
+
+// readAllChanges is a changesReader that parses the entire CHANGES content.
+func readAllChanges(r io.Reader) (*changes.Content, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return changes.Read(string(body))
+}
+
+// readTagChanges returns a changesReader that only parses the CHANGES content
+// needed to obtain the release notes for the tag with the given name.
+func readTagChanges(name string) changesReader {
+	return func(r io.Reader) (*changes.Content, error) {
+		if v, err := semver.Parse(name); err == nil {
+			return changes.ReadUntil(r, v)
 		}
-		return nil
-	})
+		return changes.ReadTop(r, 1)
+	}
+}
+
+// openBlob returns a reader that streams the raw content of the git blob with
+// the given sha. Closing the reader aborts the download.
+func (r *repo) openBlob(ctx context.Context, c *github.Client, sha string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	req, err := c.NewRequest("GET", fmt.Sprintf("repos/%v/%v/git/blobs/%v", r.owner, r.name, sha), nil)
 	if err != nil {
-		return nil, "", err
+		pw.CloseWithError(err)
+		return pr
 	}
-	return out, changesPath, nil
+	req.Header.Set("Accept", "application/vnd.github.v3.raw")
+	go func() {
+		_, err := c.Do(ctx, req, pw)
+		pw.CloseWithError(err)
+	}()
+	return pr
 }
 
 // isChangesFile returns true if the file at p could be a CHANGES file.
@@ -1031,6 +1935,26 @@ func isChangesFile(p string) bool {
 	return dir == "" && strings.Contains(name, "CHANGES")
 }
 
+// localeRE matches a language tag, such as 'zh', 'pt-BR' or 'zh_Hant'.
+var localeRE = regexp.MustCompile(`^[A-Za-z]{2,3}(?:[-_][A-Za-z0-9]{2,8})*$`)
+
+// changesLocale returns the locale of the CHANGES file at p, or an empty string
+// if the file is not a translation. Translations are named
+// CHANGES.<locale>.md, for example the locale of CHANGES.zh.md is 'zh'.
+// Other names, such as backups like CHANGES.md.bak, are not translations.
+func changesLocale(p string) string {
+	_, name := path.Split(p)
+	parts := strings.Split(name, ".")
+	if len(parts) != 3 || !localeRE.MatchString(parts[1]) {
+		return ""
+	}
+	switch strings.ToLower(parts[2]) {
+	case "md", "markdown", "txt", "rst":
+		return parts[1]
+	}
+	return ""
+}
+
 // validate looks for and returns a list of problems found with the current
 // release branches, tags and CHANGES of the repo r.
 func (r *repo) validate(ctx context.Context, u ui.UI) ([]string, error) {
@@ -1042,12 +1966,15 @@ func (r *repo) validate(ctx context.Context, u ui.UI) ([]string, error) {
 
 	for _, b := range r.branches {
 		isDevelopementBranch := r.mainBranch == b
-		b.problems = append(b.problems, b.changes.Validate(isDevelopementBranch)...)
+		b.problems = append(b.problems, b.changes.ValidateWithFlavorOrder(isDevelopementBranch, r.flavorOrder)...)
 
 		for _, v := range b.changes.Versions() {
 			if v.Flavor != "" {
 				continue
 			}
+			if v.Epoch != 0 {
+				continue // ':' is not allowed in git ref names, so epochs have no branch or tag
+			}
 			if r.mainBranch == b {
 				vBranchName := r.branchNameForVersion(v)
 				if _, found := r.branches[vBranchName]; !found {
@@ -1076,6 +2003,19 @@ func (r *repo) validate(ctx context.Context, u ui.UI) ([]string, error) {
 			}
 		}
 
+		for _, l := range b.translations.locales() {
+			t := b.translations[l]
+			diff := changes.Compare(b.changes, t.changes)
+			for _, v := range diff.OnlyInA {
+				b.problems = append(b.problems,
+					fmt.Errorf("Translation %v is missing version %v found in %v", t.path, v, b.changesPath))
+			}
+			for _, v := range diff.OnlyInB {
+				b.problems = append(b.problems,
+					fmt.Errorf("Translation %v has version %v not found in %v", t.path, v, b.changesPath))
+			}
+		}
+
 		for _, p := range b.problems {
 			problems = append(problems, fmt.Sprintf("Branch '%v': %v", b.name, p))
 		}
@@ -1099,6 +2039,16 @@ func parseReleaseBranch(s string) *int {
 	return &major
 }
 
+// branchNames returns the sorted names of the branches of the repo.
+func (r repo) branchNames() []string {
+	out := make([]string, 0, len(r.branches))
+	for name := range r.branches {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
 // branchNameForVersion returns the style-formatted release branch name for
 // the version v.
 func (r repo) branchNameForVersion(v semver.Version) string {
@@ -1114,8 +2064,94 @@ func (r repo) tagNameForVersion(v semver.Version) string {
 	return r.versionStyle.Format(v)
 }
 
+// previousRelease returns the most recent released version in c that is
+// older than v, along with the commit hash of its release tag. If there is no
+// such release, or it has no tag, then previousRelease returns false.
+func (r repo) previousRelease(c *changes.Content, v semver.Version) (semver.Version, git.Hash, bool) {
+	older := semver.List{}
+	for _, rv := range c.Versions().Released() {
+		if semver.Compare(rv, v, false) < 0 {
+			older = append(older, rv)
+		}
+	}
+	prev, ok := older.Max()
+	if !ok {
+		return semver.Version{}, git.Hash{}, false
+	}
+	t, ok := r.tags[r.tagNameForVersion(prev)]
+	if !ok {
+		return semver.Version{}, git.Hash{}, false
+	}
+	return prev, git.ParseHash(t.sha), true
+}
+
 // releaseNameForVersion returns the style-formatted release name for the
 // version v.
 func (r repo) releaseNameForVersion(v semver.Version) string {
 	return r.versionStyle.Format(v)
 }
+
+// gitCredentials returns the credentials used to authenticate with the git
+// remotes of r.
+func (r repo) gitCredentials(cred credentials) git.Credentials {
+	return git.Credentials{
+		Username: cred.Username,
+		Password: cred.AccessToken,
+		SSHKey:   r.sshKey,
+	}
+}
+
+// pushFlags returns the flags used to push changes to r.
+func (r repo) pushFlags(cred credentials) git.PushFlags {
+	return git.PushFlags{Credentials: r.gitCredentials(cred)}
+}
+
+// checkoutDir returns the path of the temporary checkout directory of r.
+// Windows limits paths to 260 characters, so on Windows a short, hashed
+// directory name is used to leave room for the paths of the repository's
+// files.
+func (r repo) checkoutDir() string {
+	if runtime.GOOS == "windows" {
+		hash := sha1.Sum([]byte(r.owner + "/" + r.name))
+		return filepath.Join(os.TempDir(), "rm-"+hex.EncodeToString(hash[:4]))
+	}
+	return filepath.Join(os.TempDir(), "release-me", r.owner, r.name)
+}
+
+// pushToMirrors calls push for each of the mirror remotes of r, with the
+// flags used to push to that mirror. Mirrors are only force-pushed if
+// forceMirrors is set. A failure to push to a mirror does not fail the
+// release, so pushToMirrors returns a description of each failure instead of an
+// error.
+func (r repo) pushToMirrors(cred credentials, push func(url string, flags git.PushFlags) error) []string {
+	failures := []string{}
+	for _, url := range r.mirrors {
+		mc := cred.Mirrors[url]
+		flags := git.PushFlags{
+			Credentials: git.Credentials{
+				Username: mc.Username,
+				Password: mc.AccessToken,
+				SSHKey:   mc.SSHKey,
+			},
+			Force: r.forceMirrors,
+		}
+		if err := push(url, flags); err != nil {
+			failures = append(failures, fmt.Sprintf("%v: %v", url, strings.SplitN(err.Error(), "\n", 2)[0]))
+		}
+	}
+	return failures
+}
+
+// checkoutFlags returns the flags used for the temporary checkouts of r.
+// The full commit history is fetched, as it is needed to scan for releases and
+// to rebase release branches. For public repositories, file contents are
+// fetched on demand, as release-me usually only reads and writes the changes
+// files of the most recent commit.
+// Private repositories are fetched in full, as on demand fetches are not
+// authenticated.
+func (r repo) checkoutFlags(cred credentials) git.CheckoutFlags {
+	if r.private {
+		return git.CheckoutFlags{Credentials: r.gitCredentials(cred)}
+	}
+	return git.CheckoutFlags{Filter: "blob:none"}
+}
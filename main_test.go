@@ -0,0 +1,136 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ben-clayton/release-me/changes"
+	"github.com/google/go-github/v32/github"
+)
+
+func TestChangesLocale(t *testing.T) {
+	for _, test := range []struct {
+		path   string
+		expect string
+	}{
+		{"CHANGES.md", ""},
+		{"CHANGES", ""},
+		{"CHANGES.zh.md", "zh"},
+		{"CHANGES.pt-BR.md", "pt-BR"},
+		{"CHANGES.zh_Hant.markdown", "zh_Hant"},
+		{"CHANGES.fr.txt", "fr"},
+		{"CHANGES.de.RST", "de"},
+		{"CHANGES.md.bak", ""},
+		{"CHANGES.zh.md.bak", ""},
+		{"CHANGES.zh", ""},
+		{"CHANGES.backup.md", ""},
+		{"CHANGES.a.md", ""},
+		{"CHANGES.zh-.md", ""},
+		{"CHANGES.1.md", ""},
+		{"docs/CHANGES.zh.md", "zh"},
+	} {
+		if got := changesLocale(test.path); got != test.expect {
+			t.Errorf("changesLocale('%v') returned '%v', expected '%v'", test.path, got, test.expect)
+		}
+	}
+}
+
+func TestReadChangesTree(t *testing.T) {
+	blob := func(path string) *github.TreeEntry {
+		return &github.TreeEntry{Path: github.String(path), SHA: github.String(path), Type: github.String("blob")}
+	}
+	files := map[string]string{
+		"CHANGES.md":     "## 1.1.0\n\n## 1.0.0\n",
+		"CHANGES.zh.md":  "## 1.1.0\n\n## 1.0.0\n",
+		"CHANGES.fr.md":  "## 1.1.0\n\n## 99999999999999999999.0.0\n",
+		"CHANGES.md.bak": "not a changes file",
+	}
+	fetch := func(path, sha string) (*changes.Content, error) {
+		c, err := changes.Read(files[sha])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse %v: %w", path, err)
+		}
+		return c, nil
+	}
+
+	entries := []*github.TreeEntry{
+		blob("CHANGES.fr.md"),
+		blob("CHANGES.md"),
+		blob("CHANGES.md.bak"),
+		blob("CHANGES.zh.md"),
+		{Path: github.String("CHANGES.de.md"), Type: github.String("tree")},
+		blob("README.md"),
+	}
+	content, path, localized, problems, err := readChangesTree(entries, fetch)
+	if err != nil {
+		t.Fatalf("readChangesTree() returned error: %v", err)
+	}
+	if path != "CHANGES.md" || content == nil {
+		t.Errorf("readChangesTree() returned CHANGES path '%v', expected 'CHANGES.md'", path)
+	}
+	if got := localized.locales(); !reflect.DeepEqual(got, []string{"zh"}) {
+		t.Errorf("readChangesTree() returned translations %v, expected [zh]", got)
+	}
+	if len(problems) != 1 {
+		t.Fatalf("readChangesTree() returned problems %v, expected a problem with CHANGES.fr.md", problems)
+	}
+	if msg := problems[0].Error(); !strings.Contains(msg, "CHANGES.fr.md") {
+		t.Errorf("readChangesTree() returned problem '%v', expected a problem with CHANGES.fr.md", msg)
+	}
+
+	if _, _, _, _, err := readChangesTree([]*github.TreeEntry{blob("CHANGES.zh.md")}, fetch); err != errNoChangesFile {
+		t.Errorf("readChangesTree() without a CHANGES file returned error '%v', expected '%v'", err, errNoChangesFile)
+	}
+
+	files["CHANGES.md"] = files["CHANGES.fr.md"]
+	if _, _, _, _, err := readChangesTree(entries, fetch); err == nil {
+		t.Errorf("readChangesTree() with an unparsable CHANGES file did not return an error")
+	}
+}
+
+func TestParseGitTimeouts(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		def      time.Duration
+		timeouts map[string]time.Duration
+	}{
+		{"", 0, map[string]time.Duration{}},
+		{"30m", 30 * time.Minute, map[string]time.Duration{}},
+		{"fetch=1h", 0, map[string]time.Duration{"fetch": time.Hour}},
+		{"10m,fetch=1h,push=20m", 10 * time.Minute, map[string]time.Duration{"fetch": time.Hour, "push": 20 * time.Minute}},
+		{" 10m , fetch = 1h30m ", 10 * time.Minute, map[string]time.Duration{"fetch": 90 * time.Minute}},
+		{"1m,2m", 2 * time.Minute, map[string]time.Duration{}},
+	} {
+		def, timeouts, err := parseGitTimeouts(test.s)
+		if err != nil {
+			t.Errorf("parseGitTimeouts('%v') returned error: %v", test.s, err)
+			continue
+		}
+		if def != test.def || !reflect.DeepEqual(timeouts, test.timeouts) {
+			t.Errorf("parseGitTimeouts('%v') returned %v, %v, expected %v, %v", test.s, def, timeouts, test.def, test.timeouts)
+		}
+	}
+
+	for _, s := range []string{"30", "abc", "0s", "-1m", "fetch=", "fetch=1x", "=1m", "10m,", "fetch=1h=2h"} {
+		if _, _, err := parseGitTimeouts(s); err == nil {
+			t.Errorf("parseGitTimeouts('%v') did not return an error", s)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a version requirement, which can be used to select versions
+// from a range. Constraints are created with ParseConstraint.
+type Constraint struct {
+	str    string
+	ranges [][]comparator // Alternatives ('||') of ranges (space separated terms)
+}
+
+// comparator is a single version comparison.
+type comparator struct {
+	op string // One of "=", "!=", "<", "<=", ">", ">="
+	v  Version
+}
+
+// ParseConstraint parses the version constraint from s.
+// Constraints are made up of whitespace or comma separated terms, all of which
+// must match. Alternative constraints can be separated with '||'.
+// Supported terms are:
+//   1.2.3    exactly 1.2.3
+//   =1.2.3   exactly 1.2.3
+//   !=1.2.3  any version but 1.2.3
+//   >1.2.3   greater than 1.2.3
+//   >=1.2.3  greater than or equal to 1.2.3
+//   <1.2.3   less than 1.2.3
+//   <=1.2.3  less than or equal to 1.2.3
+//   ^1.2.3   >=1.2.3 <2.0.0 (or <0.3.0 for 0.2.3)
+//   ~1.2.3   >=1.2.3 <1.3.0
+//   1.2.x    >=1.2.0 <1.3.0 ('*' and 'X' may also be used as wildcards)
+// Missing components of a version are treated as 0, or as a wildcard for
+// versions without an operator.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{str: s}
+	for _, alt := range strings.Split(s, "||") {
+		terms := strings.FieldsFunc(alt, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("Empty version constraint in '%v'", s)
+		}
+		r := []comparator{}
+		for _, term := range terms {
+			cmps, err := parseTerm(term)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot parse version constraint '%v': %w", s, err)
+			}
+			r = append(r, cmps...)
+		}
+		c.ranges = append(c.ranges, r)
+	}
+	return c, nil
+}
+
+// MustParseConstraint parses the version constraint from s, panicking if the
+// constraint could not be parsed.
+func MustParseConstraint(s string) *Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c Constraint) String() string { return c.str }
+
+// Matches returns true if the version v satisfies the constraint.
+func (c Constraint) Matches(v Version) bool {
+	for _, r := range c.ranges {
+		if matchesAll(r, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAll(cmps []comparator, v Version) bool {
+	for _, c := range cmps {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c comparator) matches(v Version) bool {
+	cmp := Compare(v, c.v, true)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// parseTerm parses a single constraint term into one or more comparators.
+func parseTerm(term string) ([]comparator, error) {
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if !strings.HasPrefix(term, op) {
+			continue
+		}
+		v, n, err := parsePartial(term[len(op):])
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "^":
+			upper := Version{Epoch: v.Epoch, Major: v.Major + 1}
+			switch {
+			case v.Major == 0 && v.Minor == 0 && n == 3:
+				upper = Version{Epoch: v.Epoch, Patch: v.Patch + 1}
+			case v.Major == 0 && n >= 2:
+				upper = Version{Epoch: v.Epoch, Minor: v.Minor + 1}
+			}
+			return []comparator{{">=", v}, {"<", upper}}, nil
+		case "~":
+			upper := Version{Epoch: v.Epoch, Major: v.Major, Minor: v.Minor + 1}
+			if n == 1 {
+				upper = Version{Epoch: v.Epoch, Major: v.Major + 1}
+			}
+			return []comparator{{">=", v}, {"<", upper}}, nil
+		default:
+			return []comparator{{op, v}}, nil
+		}
+	}
+
+	// No operator. Either an exact version, or one with wildcards.
+	v, n, err := parsePartial(term)
+	if err != nil {
+		return nil, err
+	}
+	switch n {
+	case 0:
+		if v.Epoch != 0 {
+			return []comparator{{">=", v}, {"<", Version{Epoch: v.Epoch + 1}}}, nil
+		}
+		return []comparator{}, nil // Matches everything
+	case 1:
+		return []comparator{{">=", v}, {"<", Version{Epoch: v.Epoch, Major: v.Major + 1}}}, nil
+	case 2:
+		return []comparator{{">=", v}, {"<", Version{Epoch: v.Epoch, Major: v.Major, Minor: v.Minor + 1}}}, nil
+	default:
+		return []comparator{{"=", v}}, nil
+	}
+}
+
+// parsePartial parses a version that may be missing trailing components, or
+// use wildcards ('x', 'X' or '*') in place of components. parsePartial returns
+// the parsed version, along with the number of numerical components parsed.
+func parsePartial(s string) (Version, int, error) {
+	epoch, str, err := splitEpoch(s)
+	if err != nil {
+		return Version{}, 0, err
+	}
+	v := Version{Epoch: epoch}
+	str = strings.TrimPrefix(str, "v")
+	if i := strings.Index(str, "+"); i >= 0 {
+		str, v.Metadata = str[:i], str[i+1:]
+	}
+	if i := strings.Index(str, "-"); i >= 0 {
+		str, v.Flavor = str[:i], str[i+1:]
+	}
+	parts := strings.Split(str, ".")
+	if len(parts) > 4 {
+		return Version{}, 0, fmt.Errorf("Too many version components in '%v'", s)
+	}
+	components := []*int{&v.Major, &v.Minor, &v.Patch, &v.Build}
+	n := 0
+	for i, p := range parts {
+		if p == "x" || p == "X" || p == "*" {
+			break
+		}
+		val, err := strconv.Atoi(p)
+		if err != nil || val < 0 {
+			return Version{}, 0, fmt.Errorf("Cannot parse '%v' as a version", s)
+		}
+		*components[i] = val
+		n++
+	}
+	if n < len(parts) && v.Flavor != "" {
+		return Version{}, 0, fmt.Errorf("Wildcard versions cannot have a flavor '%v'", s)
+	}
+	return v, n, nil
+}
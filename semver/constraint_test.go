@@ -0,0 +1,83 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver_test
+
+import (
+	"testing"
+
+	"github.com/ben-clayton/release-me/semver"
+)
+
+func TestConstraintMatches(t *testing.T) {
+	for _, test := range []struct {
+		constraint string
+		matches    []string
+		rejects    []string
+	}{
+		{"^1.2", []string{"1.2.0", "1.2.5", "1.9.0"}, []string{"1.1.9", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.2.2", "0.3.0", "1.0.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4", "0.1.0"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.2.2", "1.3.0"}},
+		{"~1", []string{"1.0.0", "1.9.9"}, []string{"0.9.0", "2.0.0"}},
+		{">=1.0 <2.0", []string{"1.0.0", "1.5.2"}, []string{"0.9.9", "2.0.0", "2.1.0"}},
+		{">1.0, <=1.2", []string{"1.0.1", "1.2.0"}, []string{"1.0.0", "1.2.1"}},
+		{"1.2.x", []string{"1.2.0", "1.2.7"}, []string{"1.1.0", "1.3.0"}},
+		{"1.*", []string{"1.0.0", "1.9.0"}, []string{"0.1.0", "2.0.0"}},
+		{"*", []string{"0.0.1", "9.9.9"}, []string{}},
+		{"1.2.3", []string{"1.2.3", "1.2.3+build.1"}, []string{"1.2.4", "1.2.3-dev"}},
+		{"!=1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"1.x || >=3.0", []string{"1.2.0", "3.1.0"}, []string{"2.0.0", "0.1.0"}},
+		{"^1:2.3.0", []string{"1:2.3.0", "1:2.9.0"}, []string{"1:2.2.0", "1:3.0.0", "2.5.0", "2:2.5.0"}},
+		{"~1:2.3.0", []string{"1:2.3.0", "1:2.3.9"}, []string{"1:2.4.0", "2.3.5"}},
+		{"1:2.x", []string{"1:2.0.0", "1:2.5.0"}, []string{"1:3.0.0", "2.5.0"}},
+		{"1:2", []string{"1:2.0.0", "1:2.9.0"}, []string{"1:3.0.0", "2.5.0"}},
+		{"1:*", []string{"1:0.0.1", "1:9.9.9"}, []string{"9.9.9", "2:0.0.1"}},
+		{">=1:1.0", []string{"1:1.0.0", "2:0.1.0"}, []string{"1:0.9.0", "9.9.9"}},
+	} {
+		c, err := semver.ParseConstraint(test.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint('%v') returned error: %v", test.constraint, err)
+			continue
+		}
+		for _, s := range test.matches {
+			v, err := semver.Parse(s)
+			if err != nil {
+				t.Errorf("semver.Parse('%v') returned error: %v", s, err)
+				continue
+			}
+			if !c.Matches(v) {
+				t.Errorf("'%v'.Matches('%v') returned false", test.constraint, s)
+			}
+		}
+		for _, s := range test.rejects {
+			v, err := semver.Parse(s)
+			if err != nil {
+				t.Errorf("semver.Parse('%v') returned error: %v", s, err)
+				continue
+			}
+			if c.Matches(v) {
+				t.Errorf("'%v'.Matches('%v') returned true", test.constraint, s)
+			}
+		}
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, s := range []string{"", ">=", "^a.b", "1.2.3.4.5", "1 || "} {
+		if _, err := semver.ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint('%v') did not return an error", s)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StyleCandidate is a version style found by DetectStyle.
+type StyleCandidate struct {
+	Style Style
+	Uses  int // Number of names that use the style
+}
+
+// StyleReport is the result of DetectStyle.
+type StyleReport struct {
+	Candidates []StyleCandidate // Sorted from most to least used
+	Conflicts  []string         // Descriptions of inconsistent style usage
+}
+
+// DetectStyle parses the version style of each of the names, returning the
+// distinct styles found along with the number of names using each style.
+// Names that do not hold a version are ignored. Names that use the same prefix
+// are considered to use the same style.
+func DetectStyle(names []string) StyleReport {
+	type usage struct {
+		style            Style
+		uses             int
+		patch, omitPatch int
+	}
+	byPrefix := map[string]*usage{}
+	for _, name := range names {
+		s := ParseStyle(name)
+		if s == nil {
+			continue
+		}
+		u, ok := byPrefix[s.Prefix]
+		if !ok {
+			u = &usage{style: *s}
+			byPrefix[s.Prefix] = u
+		} else if merged := Merge(u.style, *s); merged != nil {
+			u.style = *merged
+		}
+		u.uses++
+		if s.OmitPatch {
+			u.omitPatch++
+		} else {
+			u.patch++
+		}
+	}
+
+	report := StyleReport{}
+	for _, u := range byPrefix {
+		report.Candidates = append(report.Candidates, StyleCandidate{u.style, u.uses})
+	}
+	sort.Slice(report.Candidates, func(i, j int) bool {
+		a, b := report.Candidates[i], report.Candidates[j]
+		if a.Uses != b.Uses {
+			return a.Uses > b.Uses
+		}
+		return a.Style.Prefix < b.Style.Prefix
+	})
+
+	if len(report.Candidates) > 1 {
+		uses := make([]string, len(report.Candidates))
+		for i, c := range report.Candidates {
+			uses[i] = fmt.Sprintf("%v (%d uses)", describePrefix(c.Style.Prefix), c.Uses)
+		}
+		report.Conflicts = append(report.Conflicts,
+			fmt.Sprintf("Multiple version prefixes are in use: %v", strings.Join(uses, ", ")))
+	}
+	for _, c := range report.Candidates {
+		if u := byPrefix[c.Style.Prefix]; u.patch > 0 && u.omitPatch > 0 {
+			report.Conflicts = append(report.Conflicts,
+				fmt.Sprintf("Versions with %v are used both with (%d uses) and without (%d uses) a patch version",
+					describePrefix(c.Style.Prefix), u.patch, u.omitPatch))
+		}
+	}
+	return report
+}
+
+// Ambiguous returns true if more than one candidate style was found.
+func (r StyleReport) Ambiguous() bool { return len(r.Candidates) > 1 }
+
+// Best returns the most used style, or false if no styles were found.
+func (r StyleReport) Best() (Style, bool) {
+	if len(r.Candidates) == 0 {
+		return Style{}, false
+	}
+	return r.Candidates[0].Style, true
+}
+
+func describePrefix(prefix string) string {
+	if prefix == "" {
+		return "no prefix"
+	}
+	return fmt.Sprintf("prefix '%v'", prefix)
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver_test
+
+import (
+	"testing"
+
+	"github.com/ben-clayton/release-me/semver"
+)
+
+func TestDetectStyle(t *testing.T) {
+	report := semver.DetectStyle([]string{
+		"v1.0.0", "v1.1.0", "v1.2", "release-0.9.0", "main", "release-2.x.x",
+	})
+	check(t, "Candidates", report.Candidates, []semver.StyleCandidate{
+		{Style: semver.Style{Prefix: "v", OmitPatch: true}, Uses: 3},
+		{Style: semver.Style{Prefix: "release-"}, Uses: 1},
+	})
+	check(t, "Conflicts", report.Conflicts, []string{
+		"Multiple version prefixes are in use: prefix 'v' (3 uses), prefix 'release-' (1 uses)",
+		"Versions with prefix 'v' are used both with (2 uses) and without (1 uses) a patch version",
+	})
+	check(t, "Ambiguous()", report.Ambiguous(), true)
+	best, ok := report.Best()
+	check(t, "Best()", best, semver.Style{Prefix: "v", OmitPatch: true})
+	check(t, "Best() ok", ok, true)
+}
+
+func TestDetectStyleUnambiguous(t *testing.T) {
+	report := semver.DetectStyle([]string{"1.0.0", "1.1.0", "feature"})
+	check(t, "Candidates", report.Candidates, []semver.StyleCandidate{
+		{Style: semver.Style{}, Uses: 2},
+	})
+	check(t, "Conflicts", len(report.Conflicts), 0)
+	check(t, "Ambiguous()", report.Ambiguous(), false)
+}
+
+func TestDetectStyleNone(t *testing.T) {
+	report := semver.DetectStyle([]string{"main"})
+	check(t, "Ambiguous()", report.Ambiguous(), false)
+	if _, ok := report.Best(); ok {
+		t.Errorf("Best() returned a style")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MarshalText implements encoding.TextMarshaler.
+// The version is encoded as returned by String().
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *Version) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+// The style is encoded as an example version formatted with the style
+// (e.g. 'v1.2.0'), which can be parsed back with ParseStyle(). If a single
+// example cannot describe the style, such as a '.' flavor separator that
+// forces the patch version to be written for a style that otherwise omits it,
+// then the style is encoded as an unflavored and a flavored example separated
+// by a space (e.g. '1.2 1.2.0.dev').
+func (s Style) MarshalText() ([]byte, error) {
+	plain := s.Format(Version{Major: 1, Minor: 2})
+	if s.FlavorSep == "" && !s.FlavorAttached {
+		return []byte(plain), nil
+	}
+	flavored := s.Format(Version{Major: 1, Minor: 2, Flavor: "dev"})
+	if parsed := ParseStyle(flavored); parsed != nil && *parsed == s {
+		return []byte(flavored), nil
+	}
+	return []byte(plain + " " + flavored), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Style) UnmarshalText(text []byte) error {
+	examples := strings.Split(string(text), " ")
+	parsed := ParseStyle(examples[0])
+	switch {
+	case parsed == nil || len(examples) > 2:
+		return fmt.Errorf("Cannot parse '%v' as a version style", string(text))
+	case len(examples) == 2:
+		flavored := ParseStyle(examples[1])
+		if flavored == nil {
+			return fmt.Errorf("Cannot parse '%v' as a version style", string(text))
+		}
+		parsed.FlavorSep, parsed.FlavorAttached = flavored.FlavorSep, flavored.FlavorAttached
+	}
+	*s = *parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+// The list is encoded as an array of version strings. A nil list is encoded as
+// an empty array.
+func (l List) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]Version(l))
+}
+
+// MarshalJSON implements json.Marshaler.
+// The set is encoded as an array of version strings, sorted from the most
+// recent to the oldest.
+func (s Set) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.List())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *Set) UnmarshalJSON(data []byte) error {
+	list := List{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list.Set()
+	return nil
+}
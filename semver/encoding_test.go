@@ -0,0 +1,119 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ben-clayton/release-me/semver"
+)
+
+func TestJSON(t *testing.T) {
+	type config struct {
+		Version semver.Version
+		Style   semver.Style
+		List    semver.List
+		Set     semver.Set
+	}
+	in := config{
+		Version: semver.Version{Major: 1, Minor: 2, Patch: 3, Flavor: "rc1"},
+		Style:   semver.Style{Prefix: "release-", OmitPatch: true, FlavorSep: "_"},
+		List:    semver.List{{Major: 2}, {Major: 1, Minor: 5}},
+		Set:     semver.List{{Major: 1}, {Major: 3, Build: 4}}.Set(),
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %v", err)
+	}
+	check(t, "json.Marshal()", string(data),
+		`{"Version":"1.2.3-rc1","Style":"release-1.2_dev","List":["2.0.0","1.5.0"],"Set":["3.0.0.4","1.0.0"]}`)
+
+	out := config{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("json.Unmarshal() returned error: %v", err)
+	}
+	check(t, "json.Unmarshal()", out, in)
+}
+
+func TestJSONEmpty(t *testing.T) {
+	data, err := json.Marshal(struct {
+		List semver.List
+		Set  semver.Set
+	}{})
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %v", err)
+	}
+	check(t, "json.Marshal()", string(data), `{"List":[],"Set":[]}`)
+}
+
+func TestUnmarshalTextErrors(t *testing.T) {
+	v := semver.Version{}
+	if err := v.UnmarshalText([]byte("not-a-version")); err == nil {
+		t.Errorf("Version.UnmarshalText() did not return an error")
+	}
+	for _, text := range []string{"not-a-style", "1.2 not-a-style", "1.2 1.2.0.dev 1.2.0_dev"} {
+		s := semver.Style{}
+		if err := s.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("Style.UnmarshalText('%v') did not return an error", text)
+		}
+	}
+}
+
+// TestStyleTextRoundTrip checks that every Style formats versions the same way
+// after a round trip through MarshalText and UnmarshalText.
+func TestStyleTextRoundTrip(t *testing.T) {
+	versions := []semver.Version{
+		{Major: 1, Minor: 2},
+		{Major: 1, Minor: 2, Patch: 3},
+		{Major: 1, Minor: 2, Flavor: "dev"},
+		{Major: 1, Minor: 2, Patch: 3, Flavor: "rc1"},
+		{Major: 1, Minor: 2, Build: 4},
+		{Major: 1, Minor: 2, Patch: 3, Build: 4, Flavor: "beta"},
+	}
+	for _, prefix := range []string{"", "v", "release-"} {
+		for _, omitPatch := range []bool{false, true} {
+			for _, build := range []bool{false, true} {
+				for _, sep := range []string{"", "-", "_", "."} {
+					for _, attached := range []bool{false, true} {
+						in := semver.Style{
+							Prefix:         prefix,
+							OmitPatch:      omitPatch,
+							Build:          build,
+							FlavorSep:      sep,
+							FlavorAttached: attached,
+						}
+						text, err := in.MarshalText()
+						if err != nil {
+							t.Errorf("%+v.MarshalText() returned error: %v", in, err)
+							continue
+						}
+						out := semver.Style{}
+						if err := out.UnmarshalText(text); err != nil {
+							t.Errorf("UnmarshalText('%v') of %+v returned error: %v", string(text), in, err)
+							continue
+						}
+						for _, v := range versions {
+							if got, expect := out.Format(v), in.Format(v); got != expect {
+								t.Errorf("UnmarshalText('%v') of %+v formats %v as '%v', expected '%v'",
+									string(text), in, v, got, expect)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FlavorOrder is a policy for ordering versions that differ only by flavor.
+// Each entry is a flavor prefix, listed from lowest to highest precedence. The
+// empty string represents the release (unflavored) version. For example:
+//   FlavorOrder{"dev", "alpha", "beta", "rc", ""}
+// orders 1.0.0-dev < 1.0.0-alpha2 < 1.0.0-beta < 1.0.0-rc1 < 1.0.0.
+// Flavors are matched against the longest matching entry, and flavors that
+// match the same entry are ordered by their remaining suffix (numerically if
+// both suffixes are numbers). Flavors that do not match any entry are ordered
+// after all the entries.
+type FlavorOrder []string
+
+// DefaultFlavorOrder is the FlavorOrder used by Compare, which orders the
+// release before any flavored version.
+var DefaultFlavorOrder = FlavorOrder{""}
+
+// ParseFlavorOrder parses the flavor ordering policy from s, which is a list of
+// flavors separated with '<' or ','. The release version is written as '""'
+// or 'release'. For example: 'dev < alpha < beta < rc < ""'.
+func ParseFlavorOrder(s string) (FlavorOrder, error) {
+	out := FlavorOrder{}
+	seen := map[string]bool{}
+	for _, entry := range strings.FieldsFunc(s, func(r rune) bool { return r == '<' || r == ',' }) {
+		entry = strings.TrimSpace(entry)
+		switch entry {
+		case "":
+			return nil, fmt.Errorf("Empty entry in flavor order '%v'", s)
+		case `""`, "''", "release":
+			entry = ""
+		}
+		if seen[entry] {
+			return nil, fmt.Errorf("Flavor '%v' is listed more than once in flavor order '%v'", entry, s)
+		}
+		seen[entry] = true
+		out = append(out, entry)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("Flavor order '%v' does not contain any flavors", s)
+	}
+	return out, nil
+}
+
+func (o FlavorOrder) String() string {
+	entries := make([]string, len(o))
+	for i, e := range o {
+		if e == "" {
+			e = `""`
+		}
+		entries[i] = e
+	}
+	return strings.Join(entries, " < ")
+}
+
+// Compare compares the versions a and b using the flavor ordering policy,
+// ignoring any build metadata, returning:
+// -1 if a < b
+//  1 if a > b
+//  0 if a == b
+func (o FlavorOrder) Compare(a, b Version) int {
+	if c := Compare(a, b, false); c != 0 {
+		return c
+	}
+	return o.CompareFlavors(a.Flavor, b.Flavor)
+}
+
+// CompareFlavors compares the version flavors a and b using the flavor
+// ordering policy, returning:
+// -1 if a < b
+//  1 if a > b
+//  0 if a == b
+func (o FlavorOrder) CompareFlavors(a, b string) int {
+	ia, sa := o.match(a)
+	ib, sb := o.match(b)
+	switch {
+	case ia < ib:
+		return -1
+	case ia > ib:
+		return 1
+	}
+	na, errA := strconv.Atoi(sa)
+	nb, errB := strconv.Atoi(sb)
+	if errA == nil && errB == nil {
+		sa, sb = "", ""
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+	}
+	switch {
+	case sa < sb:
+		return -1
+	case sa > sb:
+		return 1
+	}
+	return 0
+}
+
+// Contains returns true if the flavor matches one of the policy's entries.
+func (o FlavorOrder) Contains(flavor string) bool {
+	i, _ := o.match(flavor)
+	return i < len(o)
+}
+
+// match returns the index of the longest entry that matches flavor, along with
+// the remainder of the flavor following the entry. If no entries match, then
+// match returns len(o) and flavor.
+func (o FlavorOrder) match(flavor string) (int, string) {
+	best, bestLen := len(o), -1
+	for i, e := range o {
+		switch {
+		case e == "":
+			if flavor == "" {
+				return i, ""
+			}
+		case strings.HasPrefix(flavor, e) && len(e) > bestLen:
+			best, bestLen = i, len(e)
+		}
+	}
+	if best == len(o) {
+		return best, flavor
+	}
+	return best, flavor[bestLen:]
+}
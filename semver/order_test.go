@@ -0,0 +1,73 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver_test
+
+import (
+	"testing"
+
+	"github.com/ben-clayton/release-me/semver"
+)
+
+func mustParse(t *testing.T, s string) semver.Version {
+	v, err := semver.Parse(s)
+	if err != nil {
+		t.Fatalf("semver.Parse('%v') returned error: %v", s, err)
+	}
+	return v
+}
+
+func TestFlavorOrder(t *testing.T) {
+	order, err := semver.ParseFlavorOrder(`dev < alpha < beta < rc < ""`)
+	if err != nil {
+		t.Fatalf("ParseFlavorOrder() returned error: %v", err)
+	}
+	check(t, "order", order, semver.FlavorOrder{"dev", "alpha", "beta", "rc", ""})
+	check(t, "String()", order.String(), `dev < alpha < beta < rc < ""`)
+
+	// Each version is expected to be less than the next.
+	ordered := []string{
+		"1.0.0-dev",
+		"1.0.0-alpha",
+		"1.0.0-alpha2",
+		"1.0.0-beta1",
+		"1.0.0-rc2",
+		"1.0.0-rc10",
+		"1.0.0",
+		"1.0.0-other",
+		"1.0.1-dev",
+	}
+	for i := 1; i < len(ordered); i++ {
+		a, b := mustParse(t, ordered[i-1]), mustParse(t, ordered[i])
+		check(t, "Compare("+a.String()+", "+b.String()+")", order.Compare(a, b), -1)
+		check(t, "Compare("+b.String()+", "+a.String()+")", order.Compare(b, a), 1)
+	}
+	check(t, "Compare(equal)", order.Compare(mustParse(t, "1.0.0-rc1"), mustParse(t, "1.0.0-rc1+ci")), 0)
+	check(t, "Contains(rc3)", order.Contains("rc3"), true)
+	check(t, "Contains(other)", order.Contains("other"), false)
+}
+
+func TestDefaultFlavorOrder(t *testing.T) {
+	a, b := mustParse(t, "1.0.0"), mustParse(t, "1.0.0-dev")
+	check(t, "Compare(a, b)", semver.Compare(a, b, true), -1)
+	check(t, "DefaultFlavorOrder.Compare(a, b)", semver.DefaultFlavorOrder.Compare(a, b), -1)
+}
+
+func TestParseFlavorOrderErrors(t *testing.T) {
+	for _, s := range []string{"", "dev < < rc", "rc, rc", `"" < release`} {
+		if _, err := semver.ParseFlavorOrder(s); err == nil {
+			t.Errorf("semver.ParseFlavorOrder('%v') did not return an error", s)
+		}
+	}
+}
@@ -19,39 +19,94 @@ import (
 	"regexp"
 	"sort"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 // Style represents the style used to format the semantic version
 type Style struct {
 	Prefix    string
 	OmitPatch bool
+	Build     bool // Always include the fourth, build component (1.2.3.0)
+
+	// FlavorSep is the separator between the version and flavor: "-", "_" or
+	// ".". If empty, then "-" is used, unless FlavorAttached is true.
+	FlavorSep string
+	// FlavorAttached is true if the flavor directly follows the version, without
+	// a separator (1.2.3rc1).
+	FlavorAttached bool
 }
 
 var (
-	versionRE = regexp.MustCompile(`^(?:\w*-|v)?(\d+)\.(\d+)(?:\.(\d+))?(-\w+)?$`)
-	styleRE   = regexp.MustCompile(`^(\w*-|v)?(\d+)\.(\d+)(?:\.(\d+))?(-\w+)?$`)
+	versionRE = regexp.MustCompile(`^(?:\w*-|v)?(\d+)\.(\d+)(?:\.(\d+))?(?:\.(\d+))?(-\w+|[_.]?[A-Za-z]\w*)?(\+[0-9A-Za-z.-]+)?$`)
+	styleRE   = regexp.MustCompile(`^(\w*-|v)?(\d+)\.(\d+)(?:\.(\d+))?(?:\.(\d+))?(-\w+|[_.]?[A-Za-z]\w*)?(\+[0-9A-Za-z.-]+)?$`)
 )
 
 // ParseStyle attempts to parse the semantic version style from s.
 func ParseStyle(s string) *Style {
+	_, s, err := splitEpoch(s)
+	if err != nil {
+		return nil
+	}
 	m := styleRE.FindStringSubmatch(s)
-	if len(m) == 0 {
+	if len(m) == 0 || !validFlavorSep(m[6], m[4]) {
 		return nil
 	}
-	return &Style{
+	style := &Style{
 		Prefix:    m[1],
 		OmitPatch: m[4] == "",
+		Build:     m[5] != "",
+	}
+	if m[6] != "" {
+		style.FlavorSep, _ = splitFlavor(m[6])
+		style.FlavorAttached = style.FlavorSep == ""
 	}
+	return style
+}
+
+// validFlavorSep returns false if the matched flavor string uses a '.'
+// separator, but the version has no patch component. This prevents partial or
+// wildcard versions such as '1.2.x' parsing as flavored versions.
+func validFlavorSep(flavor, patch string) bool {
+	return !strings.HasPrefix(flavor, ".") || patch != ""
+}
+
+// splitFlavor splits the matched flavor string s into its separator and the
+// flavor.
+func splitFlavor(s string) (sep, flavor string) {
+	switch s[0] {
+	case '-', '_', '.':
+		return s[:1], s[1:]
+	}
+	return "", s
 }
 
 // Format returns the version v formatted using the style.
 func (s Style) Format(v Version) string {
 	out := fmt.Sprintf("%s%d.%d", s.Prefix, v.Major, v.Minor)
-	if v.Patch != 0 || !s.OmitPatch {
+	if v.Epoch != 0 {
+		out = fmt.Sprintf("%d:%v", v.Epoch, out)
+	}
+	build := v.Build != 0 || s.Build
+	dotFlavor := v.Flavor != "" && !s.FlavorAttached && s.FlavorSep == "."
+	if v.Patch != 0 || !s.OmitPatch || build || dotFlavor {
 		out += fmt.Sprintf(".%d", v.Patch)
 	}
+	if build {
+		out += fmt.Sprintf(".%d", v.Build)
+	}
 	if v.Flavor != "" {
-		out += "-" + v.Flavor
+		switch {
+		case s.FlavorAttached:
+			out += v.Flavor
+		case s.FlavorSep != "":
+			out += s.FlavorSep + v.Flavor
+		default:
+			out += "-" + v.Flavor
+		}
+	}
+	if v.Metadata != "" {
+		out += "+" + v.Metadata
 	}
 	return out
 }
@@ -65,33 +120,53 @@ func Merge(a, b Style) *Style {
 	out := Style{}
 	out.Prefix = a.Prefix
 	out.OmitPatch = a.OmitPatch || b.OmitPatch
+	out.Build = a.Build || b.Build
+	out.FlavorSep, out.FlavorAttached = a.FlavorSep, a.FlavorAttached
+	if out.FlavorSep == "" && !out.FlavorAttached {
+		out.FlavorSep, out.FlavorAttached = b.FlavorSep, b.FlavorAttached
+	}
 	return &out
 }
 
 // Version describes a semantic version.
 type Version struct {
-	Major  int
-	Minor  int
-	Patch  int
-	Flavor string
+	Epoch    int // Optional distro-style epoch (e.g. 1:2.3.4). Takes precedence over all other components.
+	Major    int
+	Minor    int
+	Patch    int
+	Build    int // Optional fourth version component (e.g. 1.2.3.4)
+	Flavor   string
+	Metadata string // Build metadata (e.g. 'build.123'). Ignored for precedence.
 }
 
 func (v Version) String() string {
 	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Epoch != 0 {
+		s = fmt.Sprintf("%d:%v", v.Epoch, s)
+	}
+	if v.Build != 0 {
+		s += fmt.Sprintf(".%d", v.Build)
+	}
 	if v.Flavor != "" {
 		s += "-" + v.Flavor
 	}
+	if v.Metadata != "" {
+		s += "+" + v.Metadata
+	}
 	return s
 }
 
 // Parse parses the Version from the string s.
 func Parse(s string) (Version, error) {
-	m := versionRE.FindStringSubmatch(s)
-	if len(m) == 0 {
+	epoch, str, err := splitEpoch(s)
+	if err != nil {
+		return Version{}, err
+	}
+	m := versionRE.FindStringSubmatch(str)
+	if len(m) == 0 || !validFlavorSep(m[5], m[3]) {
 		return Version{}, fmt.Errorf("Cannot parse '%v' as a semantic version", s)
 	}
-	v := Version{}
-	var err error
+	v := Version{Epoch: epoch}
 	v.Major, err = strconv.Atoi(m[1])
 	if err != nil {
 		return Version{}, fmt.Errorf("Failed to parse version major '%v'", m[1])
@@ -106,24 +181,212 @@ func Parse(s string) (Version, error) {
 			return Version{}, fmt.Errorf("Failed to parse version patch '%v'", m[3])
 		}
 	}
-	if len(m[4]) > 0 {
-		v.Flavor = m[4][1:]
+	if m[4] != "" {
+		v.Build, err = strconv.Atoi(m[4])
+		if err != nil {
+			return Version{}, fmt.Errorf("Failed to parse version build '%v'", m[4])
+		}
+	}
+	if len(m[5]) > 0 {
+		_, v.Flavor = splitFlavor(m[5])
+	}
+	if len(m[6]) > 0 {
+		v.Metadata = m[6][1:]
 	}
 	return v, nil
 }
 
+// splitEpoch splits the optional epoch prefix ('<epoch>:') from s, returning
+// the epoch (0 if there is no epoch) and the rest of the string.
+func splitEpoch(s string) (int, string, error) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return 0, s, nil
+	}
+	epoch, err := strconv.Atoi(s[:i])
+	if err != nil || epoch < 0 {
+		return 0, "", fmt.Errorf("Failed to parse version epoch '%v'", s[:i])
+	}
+	return epoch, s[i+1:], nil
+}
+
+// ParseStrict parses the Version from the string s, following the Semantic
+// Versioning 2.0.0 specification (https://semver.org). Unlike Parse, the
+// version must have exactly three components, and must not have a prefix,
+// leading zeros, empty components or whitespace.
+func ParseStrict(s string) (Version, error) {
+	if strings.IndexFunc(s, unicode.IsSpace) >= 0 {
+		return Version{}, fmt.Errorf("Version '%v' contains whitespace", s)
+	}
+	v := Version{}
+	str := s
+	if i := strings.Index(str, "+"); i >= 0 {
+		str, v.Metadata = str[:i], str[i+1:]
+		if err := checkIdentifiers(s, v.Metadata, false); err != nil {
+			return Version{}, err
+		}
+	}
+	if i := strings.Index(str, "-"); i >= 0 {
+		str, v.Flavor = str[:i], str[i+1:]
+		if err := checkIdentifiers(s, v.Flavor, true); err != nil {
+			return Version{}, err
+		}
+	}
+	parts := strings.Split(str, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("Version '%v' does not have 3 components", s)
+	}
+	for i, component := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		p := parts[i]
+		if err := checkNumeric(s, p); err != nil {
+			return Version{}, err
+		}
+		*component, _ = strconv.Atoi(p)
+	}
+	return v, nil
+}
+
+// ParseLenient parses the Version from the string s, making a best-effort
+// attempt to parse versions that would be rejected by Parse. Surrounding
+// whitespace and any prefix before the first digit (e.g. 'version_') are
+// ignored.
+func ParseLenient(s string) (Version, error) {
+	str := strings.TrimSpace(s)
+	i := strings.IndexFunc(str, unicode.IsDigit)
+	if i < 0 {
+		return Version{}, fmt.Errorf("Cannot parse '%v' as a semantic version", s)
+	}
+	v, err := Parse(str[i:])
+	if err != nil {
+		return Version{}, fmt.Errorf("Cannot parse '%v' as a semantic version", s)
+	}
+	return v, nil
+}
+
+// checkNumeric returns an error if p is not a valid numeric component of the
+// version s.
+func checkNumeric(s, p string) error {
+	switch {
+	case p == "":
+		return fmt.Errorf("Version '%v' has an empty component", s)
+	case strings.IndexFunc(p, func(r rune) bool { return r < '0' || r > '9' }) >= 0:
+		return fmt.Errorf("Version '%v' has a non-numeric component '%v'", s, p)
+	case len(p) > 1 && p[0] == '0':
+		return fmt.Errorf("Version '%v' has a component with a leading zero '%v'", s, p)
+	}
+	return nil
+}
+
+// checkIdentifiers returns an error if ids is not a valid dot-separated list of
+// pre-release or build metadata identifiers of the version s.
+func checkIdentifiers(s, ids string, prerelease bool) error {
+	for _, id := range strings.Split(ids, ".") {
+		if id == "" {
+			return fmt.Errorf("Version '%v' has an empty identifier", s)
+		}
+		numeric := true
+		for _, r := range id {
+			switch {
+			case r >= '0' && r <= '9':
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '-':
+				numeric = false
+			default:
+				return fmt.Errorf("Version '%v' has an invalid identifier '%v'", s, id)
+			}
+		}
+		if prerelease && numeric && len(id) > 1 && id[0] == '0' {
+			return fmt.Errorf("Version '%v' has an identifier with a leading zero '%v'", s, id)
+		}
+	}
+	return nil
+}
+
+// Kind is an enumerator of semantic version components that can be bumped.
+type Kind int
+
+const (
+	// Major is the major version component.
+	Major Kind = iota
+	// Minor is the minor version component.
+	Minor
+	// Patch is the patch version component.
+	Patch
+)
+
+// BumpMajor returns the next major version after v.
+func (v Version) BumpMajor() Version { return Version{Epoch: v.Epoch, Major: v.Major + 1} }
+
+// BumpMinor returns the next minor version after v.
+func (v Version) BumpMinor() Version {
+	return Version{Epoch: v.Epoch, Major: v.Major, Minor: v.Minor + 1}
+}
+
+// BumpPatch returns the next patch version after v.
+func (v Version) BumpPatch() Version {
+	return Version{Epoch: v.Epoch, Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+}
+
+// Bump returns the next version after v, bumping the component k.
+func (v Version) Bump(k Kind) Version {
+	switch k {
+	case Major:
+		return v.BumpMajor()
+	case Minor:
+		return v.BumpMinor()
+	default:
+		return v.BumpPatch()
+	}
+}
+
+// NextPrerelease returns the next pre-release version with the given flavor.
+// If v is already a pre-release with the flavor, then the number suffix of the
+// flavor is incremented (1.2.0-rc1 becomes 1.2.0-rc2). If v is a pre-release
+// with a different flavor, then the flavor is replaced (1.2.0-beta3 becomes
+// 1.2.0-rc1). If v is not a pre-release, then the patch version is bumped
+// (1.2.0 becomes 1.2.1-rc1).
+func (v Version) NextPrerelease(flavor string) Version {
+	switch {
+	case v.Flavor == "":
+		out := v.BumpPatch()
+		out.Flavor = flavor + "1"
+		return out
+	case strings.HasPrefix(v.Flavor, flavor):
+		if n, err := strconv.Atoi(v.Flavor[len(flavor):]); err == nil {
+			out := v
+			out.Flavor = flavor + strconv.Itoa(n+1)
+			out.Metadata = ""
+			return out
+		}
+	}
+	out := v
+	out.Flavor = flavor + "1"
+	out.Metadata = ""
+	return out
+}
+
+// NextAfter returns the next version after the highest version in list,
+// bumping the component k. Flavored versions in list are ignored.
+func NextAfter(list List, k Kind) Version {
+	highest, _ := list.Released().Max()
+	return highest.Bump(k)
+}
+
 // Set is a set of unique versions
 type Set map[Version]struct{}
 
 // List is a list of versions
 type List []Version
 
-// Compare compares two versions, returning:
+// Compare compares two versions, ignoring any build metadata, returning:
 // -1 if a < b
 //  1 if a > b
 //  0 if a == b
 func Compare(a, b Version, compareFlavor bool) int {
 	switch {
+	case a.Epoch < b.Epoch:
+		return -1
+	case a.Epoch > b.Epoch:
+		return 1
 	case a.Major < b.Major:
 		return -1
 	case a.Major > b.Major:
@@ -136,18 +399,13 @@ func Compare(a, b Version, compareFlavor bool) int {
 		return -1
 	case a.Patch > b.Patch:
 		return 1
+	case a.Build < b.Build:
+		return -1
+	case a.Build > b.Build:
+		return 1
 	default:
 		if compareFlavor {
-			switch {
-			case a.Flavor == "" && b.Flavor != "":
-				return -1
-			case a.Flavor != "" && b.Flavor == "":
-				return 1
-			case a.Flavor < b.Flavor:
-				return -1
-			case a.Flavor > b.Flavor:
-				return 1
-			}
+			return DefaultFlavorOrder.CompareFlavors(a.Flavor, b.Flavor)
 		}
 		return 0
 	}
@@ -170,6 +428,76 @@ func (l List) Sort() {
 	sort.Slice(l, func(i, j int) bool { return Compare(l[i], l[j], true) > 0 })
 }
 
+// Max returns the highest version in the list, or false if the list is empty.
+func (l List) Max() (Version, bool) {
+	if len(l) == 0 {
+		return Version{}, false
+	}
+	max := l[0]
+	for _, v := range l[1:] {
+		if v.GreaterThan(max, true) {
+			max = v
+		}
+	}
+	return max, true
+}
+
+// Min returns the lowest version in the list, or false if the list is empty.
+func (l List) Min() (Version, bool) {
+	if len(l) == 0 {
+		return Version{}, false
+	}
+	min := l[0]
+	for _, v := range l[1:] {
+		if min.GreaterThan(v, true) {
+			min = v
+		}
+	}
+	return min, true
+}
+
+// Released returns the versions in the list that are not flavored.
+func (l List) Released() List {
+	out := List{}
+	for _, v := range l {
+		if v.Flavor == "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// LatestPerMajor returns the highest version for each major version in the
+// list, sorted from the most recent to the oldest.
+func (l List) LatestPerMajor() List {
+	type major struct{ epoch, major int }
+	latest := map[major]Version{}
+	for _, v := range l {
+		key := major{v.Epoch, v.Major}
+		if existing, ok := latest[key]; !ok || v.GreaterThan(existing, true) {
+			latest[key] = v
+		}
+	}
+	out := make(List, 0, len(latest))
+	for _, v := range latest {
+		out = append(out, v)
+	}
+	out.Sort()
+	return out
+}
+
+// Within returns the versions in the list that match the constraint c, in
+// their original order.
+func (l List) Within(c *Constraint) List {
+	out := List{}
+	for _, v := range l {
+		if c.Matches(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 // Set returns the unique versions in the list.
 func (l List) Set() Set {
 	set := Set{}
@@ -188,9 +516,21 @@ func (s Set) Remove(v Version) { delete(s, v) }
 // Contains returns true if the set s contains v.
 func (s Set) Contains(v Version) bool { _, found := s[v]; return found }
 
-// Union returns the versions found in s that are also found in o.
+// Union returns the versions found in either s or o.
 func (s Set) Union(o Set) Set {
-	out := make(Set, len(s))
+	out := make(Set, len(s)+len(o))
+	for v := range s {
+		out.Add(v)
+	}
+	for v := range o {
+		out.Add(v)
+	}
+	return out
+}
+
+// Intersect returns the versions found in s that are also found in o.
+func (s Set) Intersect(o Set) Set {
+	out := Set{}
 	for v := range o {
 		if s.Contains(v) {
 			out.Add(v)
@@ -199,6 +539,17 @@ func (s Set) Union(o Set) Set {
 	return out
 }
 
+// Difference returns the versions found in s that are not found in o.
+func (s Set) Difference(o Set) Set {
+	out := Set{}
+	for v := range s {
+		if !o.Contains(v) {
+			out.Add(v)
+		}
+	}
+	return out
+}
+
 // Clone returns a shallow copy of this Set.
 func (s Set) Clone() Set {
 	out := make(Set, len(s))
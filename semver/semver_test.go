@@ -0,0 +1,302 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package semver_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ben-clayton/release-me/semver"
+)
+
+func check(t *testing.T, name string, got, expect interface{}) {
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("%v was not as expected.\nGot:\n`%v`\nExpect:\n`%v`", name, got, expect)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		s      string
+		expect semver.Version
+	}{
+		{"1.2", semver.Version{Major: 1, Minor: 2}},
+		{"1.2.3", semver.Version{Major: 1, Minor: 2, Patch: 3}},
+		{"v1.2.3", semver.Version{Major: 1, Minor: 2, Patch: 3}},
+		{"release-1.2.3-dev", semver.Version{Major: 1, Minor: 2, Patch: 3, Flavor: "dev"}},
+		{"1.2.3+build.123", semver.Version{Major: 1, Minor: 2, Patch: 3, Metadata: "build.123"}},
+		{"v1.2.3-rc1+20200101-abc", semver.Version{Major: 1, Minor: 2, Patch: 3, Flavor: "rc1", Metadata: "20200101-abc"}},
+		{"1.2.3.4", semver.Version{Major: 1, Minor: 2, Patch: 3, Build: 4}},
+		{"v1.2.3.4-beta", semver.Version{Major: 1, Minor: 2, Patch: 3, Build: 4, Flavor: "beta"}},
+		{"1.2.3rc1", semver.Version{Major: 1, Minor: 2, Patch: 3, Flavor: "rc1"}},
+		{"1.2.3_beta2", semver.Version{Major: 1, Minor: 2, Patch: 3, Flavor: "beta2"}},
+		{"1:2.3.4", semver.Version{Epoch: 1, Major: 2, Minor: 3, Patch: 4}},
+		{"2:v1.0-rc1", semver.Version{Epoch: 2, Major: 1, Flavor: "rc1"}},
+		{"v1.2.3.dev", semver.Version{Major: 1, Minor: 2, Patch: 3, Flavor: "dev"}},
+	} {
+		got, err := semver.Parse(test.s)
+		if err != nil {
+			t.Errorf("semver.Parse('%v') returned error: %v", test.s, err)
+			continue
+		}
+		check(t, "semver.Parse('"+test.s+"')", got, test.expect)
+	}
+
+	for _, s := range []string{"", "1", "a.b.c", "1.2.3+", "1.2.3+build!", "1.2.3.4.5", "x:1.2.3", "-1:1.2.3", "1:", "1.2.x", "v1.2.dev"} {
+		if _, err := semver.Parse(s); err == nil {
+			t.Errorf("semver.Parse('%v') did not return an error", s)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	for _, test := range []struct {
+		v      semver.Version
+		expect string
+	}{
+		{semver.Version{Major: 1, Minor: 2}, "1.2.0"},
+		{semver.Version{Major: 1, Minor: 2, Patch: 3, Flavor: "dev"}, "1.2.3-dev"},
+		{semver.Version{Major: 1, Minor: 2, Patch: 3, Metadata: "build.123"}, "1.2.3+build.123"},
+		{semver.Version{Major: 1, Minor: 2, Patch: 3, Build: 4}, "1.2.3.4"},
+		{semver.Version{Epoch: 1, Major: 2, Minor: 3, Patch: 4}, "1:2.3.4"},
+	} {
+		check(t, "String()", test.v.String(), test.expect)
+	}
+}
+
+func TestCompareIgnoresMetadata(t *testing.T) {
+	a := semver.Version{Major: 1, Minor: 2, Patch: 3, Metadata: "build.1"}
+	b := semver.Version{Major: 1, Minor: 2, Patch: 3, Metadata: "build.2"}
+	check(t, "Compare()", semver.Compare(a, b, true), 0)
+}
+
+func TestCompareBuild(t *testing.T) {
+	a := semver.Version{Major: 1, Minor: 2, Patch: 3}
+	b := semver.Version{Major: 1, Minor: 2, Patch: 3, Build: 1}
+	check(t, "Compare(a, b)", semver.Compare(a, b, true), -1)
+	check(t, "Compare(b, a)", semver.Compare(b, a, true), 1)
+}
+
+func TestCompareEpoch(t *testing.T) {
+	a := semver.Version{Major: 9, Minor: 9, Patch: 9}
+	b := semver.Version{Epoch: 1, Major: 1}
+	check(t, "Compare(a, b)", semver.Compare(a, b, true), -1)
+	check(t, "Compare(b, a)", semver.Compare(b, a, true), 1)
+	check(t, "BumpMinor()", b.BumpMinor(), semver.Version{Epoch: 1, Major: 1, Minor: 1})
+}
+
+func TestStyleFormat(t *testing.T) {
+	for _, test := range []struct {
+		s      string
+		v      semver.Version
+		expect string
+	}{
+		{"v1.2.3", semver.Version{Major: 4, Minor: 5}, "v4.5.0"},
+		{"release-1.2", semver.Version{Major: 4, Minor: 5}, "release-4.5"},
+		{"release-1.2", semver.Version{Major: 4, Minor: 5, Patch: 6}, "release-4.5.6"},
+		{"1.2.3", semver.Version{Major: 4, Minor: 5, Metadata: "ci.7"}, "4.5.0+ci.7"},
+		{"v1.2.3.4", semver.Version{Major: 4, Minor: 5}, "v4.5.0.0"},
+		{"release-1.2", semver.Version{Major: 4, Minor: 5, Build: 7}, "release-4.5.0.7"},
+	} {
+		style := semver.ParseStyle(test.s)
+		if style == nil {
+			t.Errorf("semver.ParseStyle('%v') returned nil", test.s)
+			continue
+		}
+		check(t, "Format()", style.Format(test.v), test.expect)
+	}
+}
+
+func TestStyleRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"1.2.3",
+		"v1.2.3-rc1",
+		"1.2.3rc1",
+		"1.2.3_beta2",
+		"release-1.2.3.dev",
+		"1.2.3.4b1+ci.5",
+		"3:v1.2",
+	} {
+		style := semver.ParseStyle(s)
+		if style == nil {
+			t.Errorf("semver.ParseStyle('%v') returned nil", s)
+			continue
+		}
+		v, err := semver.Parse(s)
+		if err != nil {
+			t.Errorf("semver.Parse('%v') returned error: %v", s, err)
+			continue
+		}
+		check(t, "Format()", style.Format(v), s)
+	}
+}
+
+func TestParseStyleDotFlavorWithoutPatch(t *testing.T) {
+	for _, s := range []string{"1.2.x", "v1.2.dev", "release-2.x"} {
+		if style := semver.ParseStyle(s); style != nil {
+			t.Errorf("semver.ParseStyle('%v') returned %+v, expected nil", s, *style)
+		}
+	}
+	// A '.' flavor separator requires the patch, even if the style omits it.
+	style := semver.Style{Prefix: "v", OmitPatch: true, FlavorSep: "."}
+	check(t, "Format()", style.Format(semver.Version{Major: 1, Minor: 2, Flavor: "dev"}), "v1.2.0.dev")
+}
+
+func TestBump(t *testing.T) {
+	v := semver.Version{Major: 1, Minor: 2, Patch: 3, Flavor: "dev", Metadata: "ci.1"}
+	check(t, "BumpMajor()", v.BumpMajor(), semver.Version{Major: 2})
+	check(t, "BumpMinor()", v.BumpMinor(), semver.Version{Major: 1, Minor: 3})
+	check(t, "BumpPatch()", v.BumpPatch(), semver.Version{Major: 1, Minor: 2, Patch: 4})
+	check(t, "Bump(Minor)", v.Bump(semver.Minor), semver.Version{Major: 1, Minor: 3})
+}
+
+func TestNextPrerelease(t *testing.T) {
+	for _, test := range []struct {
+		v      semver.Version
+		flavor string
+		expect semver.Version
+	}{
+		{semver.Version{Major: 1, Minor: 2}, "rc", semver.Version{Major: 1, Minor: 2, Patch: 1, Flavor: "rc1"}},
+		{semver.Version{Major: 1, Minor: 2, Flavor: "rc1"}, "rc", semver.Version{Major: 1, Minor: 2, Flavor: "rc2"}},
+		{semver.Version{Major: 1, Minor: 2, Flavor: "rc9"}, "rc", semver.Version{Major: 1, Minor: 2, Flavor: "rc10"}},
+		{semver.Version{Major: 1, Minor: 2, Flavor: "beta3"}, "rc", semver.Version{Major: 1, Minor: 2, Flavor: "rc1"}},
+		{semver.Version{Major: 1, Minor: 2, Flavor: "rc"}, "rc", semver.Version{Major: 1, Minor: 2, Flavor: "rc1"}},
+	} {
+		check(t, "NextPrerelease()", test.v.NextPrerelease(test.flavor), test.expect)
+	}
+}
+
+func TestNextAfter(t *testing.T) {
+	list := semver.List{
+		{Major: 2, Minor: 2, Patch: 1, Flavor: "dev"},
+		{Major: 1, Minor: 0, Patch: 0},
+		{Major: 2, Minor: 1, Patch: 4},
+		{Major: 2, Minor: 0, Patch: 0},
+	}
+	check(t, "NextAfter(Major)", semver.NextAfter(list, semver.Major), semver.Version{Major: 3})
+	check(t, "NextAfter(Minor)", semver.NextAfter(list, semver.Minor), semver.Version{Major: 2, Minor: 2})
+	check(t, "NextAfter(Patch)", semver.NextAfter(list, semver.Patch), semver.Version{Major: 2, Minor: 1, Patch: 5})
+	check(t, "NextAfter(empty)", semver.NextAfter(semver.List{}, semver.Minor), semver.Version{Minor: 1})
+}
+
+func TestSetAlgebra(t *testing.T) {
+	v1 := semver.Version{Major: 1}
+	v2 := semver.Version{Major: 2}
+	v3 := semver.Version{Major: 3}
+	a := semver.List{v1, v2}.Set()
+	b := semver.List{v2, v3}.Set()
+	check(t, "Union()", a.Union(b).List(), semver.List{v3, v2, v1})
+	check(t, "Intersect()", a.Intersect(b).List(), semver.List{v2})
+	check(t, "Difference(a, b)", a.Difference(b).List(), semver.List{v1})
+	check(t, "Difference(b, a)", b.Difference(a).List(), semver.List{v3})
+	check(t, "Union(empty)", a.Union(semver.Set{}).List(), semver.List{v2, v1})
+	check(t, "Intersect(empty)", a.Intersect(semver.Set{}).List(), semver.List{})
+	check(t, "a", a.List(), semver.List{v2, v1}) // Unmodified
+}
+
+func TestParseStrict(t *testing.T) {
+	for _, test := range []struct {
+		s      string
+		expect semver.Version
+	}{
+		{"0.0.0", semver.Version{}},
+		{"1.2.3", semver.Version{Major: 1, Minor: 2, Patch: 3}},
+		{"10.20.30-rc.1", semver.Version{Major: 10, Minor: 20, Patch: 30, Flavor: "rc.1"}},
+		{"1.2.3-x-y.0+build.007", semver.Version{Major: 1, Minor: 2, Patch: 3, Flavor: "x-y.0", Metadata: "build.007"}},
+	} {
+		got, err := semver.ParseStrict(test.s)
+		if err != nil {
+			t.Errorf("semver.ParseStrict('%v') returned error: %v", test.s, err)
+			continue
+		}
+		check(t, "semver.ParseStrict('"+test.s+"')", got, test.expect)
+	}
+
+	for _, s := range []string{
+		"", "1.2", "v1.2.3", "release-1.2.3", "1.2.3.4", "01.2.3", "1.02.3", "1.2.03",
+		"1..3", "1.2.", " 1.2.3", "1.2.3 ", "1.2.3-", "1.2.3-rc..1", "1.2.3-01",
+		"1.2.3+", "1.2.3+build_1",
+	} {
+		if _, err := semver.ParseStrict(s); err == nil {
+			t.Errorf("semver.ParseStrict('%v') did not return an error", s)
+		}
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	for _, test := range []struct {
+		s      string
+		expect semver.Version
+	}{
+		{"1.2.3", semver.Version{Major: 1, Minor: 2, Patch: 3}},
+		{"version_1.2.3", semver.Version{Major: 1, Minor: 2, Patch: 3}},
+		{" Release 1.2-beta ", semver.Version{Major: 1, Minor: 2, Flavor: "beta"}},
+		{"v01.02.03", semver.Version{Major: 1, Minor: 2, Patch: 3}},
+	} {
+		got, err := semver.ParseLenient(test.s)
+		if err != nil {
+			t.Errorf("semver.ParseLenient('%v') returned error: %v", test.s, err)
+			continue
+		}
+		check(t, "semver.ParseLenient('"+test.s+"')", got, test.expect)
+	}
+
+	for _, s := range []string{"", "version", "1", "1.2.3.4.5"} {
+		if _, err := semver.ParseLenient(s); err == nil {
+			t.Errorf("semver.ParseLenient('%v') did not return an error", s)
+		}
+	}
+}
+
+func TestListHelpers(t *testing.T) {
+	list := semver.List{
+		{Major: 2, Minor: 1},
+		{Major: 1, Minor: 4, Patch: 2},
+		{Major: 2, Minor: 2, Flavor: "dev"},
+		{Major: 1, Minor: 4, Patch: 1},
+		{Major: 0, Minor: 9},
+		{Major: 2, Minor: 0, Patch: 5},
+	}
+	max, ok := list.Max()
+	check(t, "Max()", max, semver.Version{Major: 2, Minor: 2, Flavor: "dev"})
+	check(t, "Max() ok", ok, true)
+	min, ok := list.Min()
+	check(t, "Min()", min, semver.Version{Major: 0, Minor: 9})
+	check(t, "Min() ok", ok, true)
+	check(t, "Released()", list.Released(), semver.List{
+		{Major: 2, Minor: 1},
+		{Major: 1, Minor: 4, Patch: 2},
+		{Major: 1, Minor: 4, Patch: 1},
+		{Major: 0, Minor: 9},
+		{Major: 2, Minor: 0, Patch: 5},
+	})
+	check(t, "LatestPerMajor()", list.LatestPerMajor(), semver.List{
+		{Major: 2, Minor: 2, Flavor: "dev"},
+		{Major: 1, Minor: 4, Patch: 2},
+		{Major: 0, Minor: 9},
+	})
+	check(t, "Within()", list.Within(semver.MustParseConstraint("~1.4 || >=2.0.5 <2.1")), semver.List{
+		{Major: 1, Minor: 4, Patch: 2},
+		{Major: 1, Minor: 4, Patch: 1},
+		{Major: 2, Minor: 0, Patch: 5},
+	})
+
+	if _, ok := (semver.List{}).Max(); ok {
+		t.Errorf("Max() of empty list returned true")
+	}
+	if _, ok := (semver.List{}).Min(); ok {
+		t.Errorf("Min() of empty list returned true")
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// editor returns the command line of the user's preferred text editor, taken
+// from the VISUAL or EDITOR environment variables.
+func editor() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if cmd := strings.Fields(os.Getenv(env)); len(cmd) > 0 {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// runEditor writes text to a temporary file, opens the file with the editor
+// cmd, and then reads back the edited text.
+func runEditor(cmd []string, text *string) error {
+	f, err := ioutil.TempFile("", "release-me-*.md")
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary file: %w", err)
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	_, err = f.WriteString(*text)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("Failed to write temporary file '%v': %w", path, err)
+	}
+
+	c := exec.Command(cmd[0], append(cmd[1:], path)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("Editor '%v' failed: %w", strings.Join(cmd, " "), err)
+	}
+
+	edited, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Failed to read temporary file '%v': %w", path, err)
+	}
+	*text = strings.TrimRight(string(edited), "\n")
+	return nil
+}
+
+// EditText opens the text in the editor given by the VISUAL or EDITOR
+// environment variables. If neither is set, then the text is displayed
+// unchanged.
+func (stdUI) EditText(title string, text *string) error {
+	cmd := editor()
+	if cmd == nil {
+		fmt.Printf("%s\n\n%s\n\n", title, *text)
+		fmt.Printf("Set $EDITOR to edit the text.\n\n")
+		return nil
+	}
+	fmt.Printf("%s: waiting for '%v' to exit...\n", title, strings.Join(cmd, " "))
+	return runEditor(cmd, text)
+}
+
+// EditText displays a multi-line text editor. The arrow, home, end, page up
+// and page down keys move the cursor, ctrl-s accepts the edited text, and
+// escape discards the changes.
+func (u *tcellUI) EditText(title string, text *string) error {
+	lines := [][]rune{}
+	for _, l := range strings.Split(*text, "\n") {
+		lines = append(lines, []rune(l))
+	}
+	row, col, scroll, hscroll := 0, 0, 0, 0
+
+	defer u.Clear()
+	defer u.HideCursor()
+
+	for true {
+		u.Clear()
+
+		w, h := u.Size()
+		w -= 4 // Margins
+		h -= 4 // App title, paged title, status and off by one reported by Size()
+		u.SetContent(1, 2, ' ', []rune(title+" [ctrl-s: accept, esc: cancel]"), tcell.StyleDefault)
+
+		row = clamp(row, 0, len(lines)-1)
+		col = clamp(col, 0, len(lines[row]))
+		if h > 0 && w > 0 {
+			scroll = clamp(scroll, max(row-h+1, 0), row)
+			hscroll = clamp(hscroll, max(col-w+1, 0), col)
+			for i := 0; i < h && i+scroll < len(lines); i++ {
+				l := lines[i+scroll]
+				if hscroll < len(l) {
+					l = l[hscroll:]
+				} else {
+					l = nil
+				}
+				if len(l) > w {
+					l = l[:w]
+				}
+				u.SetContent(2, i+3, ' ', l, tcell.StyleDefault)
+			}
+			u.ShowCursor(3+col-hscroll, 3+row-scroll)
+		}
+
+		u.status = fmt.Sprintf("Line %d/%d, column %d", row+1, len(lines), col+1)
+		u.present()
+
+		event, ok := u.PollEvent().(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		line := lines[row]
+		switch event.Key() {
+		case tcell.KeyEsc:
+			return ErrUserPressedEscape
+		case tcell.KeyCtrlS:
+			out := make([]string, len(lines))
+			for i, l := range lines {
+				out[i] = string(l)
+			}
+			*text = strings.Join(out, "\n")
+			return nil
+		case tcell.KeyUp:
+			row--
+		case tcell.KeyDown:
+			row++
+		case tcell.KeyLeft:
+			if col > 0 {
+				col--
+			} else if row > 0 {
+				row--
+				col = len(lines[row])
+			}
+		case tcell.KeyRight:
+			if col < len(line) {
+				col++
+			} else if row < len(lines)-1 {
+				row, col = row+1, 0
+			}
+		case tcell.KeyHome:
+			col = 0
+		case tcell.KeyEnd:
+			col = len(line)
+		case tcell.KeyPgUp:
+			row -= max(h, 1)
+		case tcell.KeyPgDn:
+			row += max(h, 1)
+		case tcell.KeyEnter:
+			tail := append([]rune{}, line[col:]...)
+			lines[row] = line[:col]
+			lines = append(lines[:row+1], append([][]rune{tail}, lines[row+1:]...)...)
+			row, col = row+1, 0
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			switch {
+			case col > 0:
+				lines[row] = append(line[:col-1], line[col:]...)
+				col--
+			case row > 0:
+				col = len(lines[row-1])
+				lines[row-1] = append(lines[row-1], line...)
+				lines = append(lines[:row], lines[row+1:]...)
+				row--
+			}
+		case tcell.KeyDelete:
+			switch {
+			case col < len(line):
+				lines[row] = append(line[:col], line[col+1:]...)
+			case row < len(lines)-1:
+				lines[row] = append(line, lines[row+1]...)
+				lines = append(lines[:row+1], lines[row+2:]...)
+			}
+		case tcell.KeyRune:
+			lines[row] = append(line[:col], append([]rune{event.Rune()}, line[col:]...)...)
+			col++
+		}
+	}
+	panic("unreachable")
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Answer is a predetermined response to a prompt of a scripted UI.
+type Answer struct {
+	// If non-empty, the title of the prompt must contain Title.
+	Title string `json:"title,omitempty"`
+
+	// ShowMenu: the text of the option to select.
+	Option string `json:"option,omitempty"`
+
+	// ShowForm: the values to assign to the fields, by field name. Fields
+	// that are not listed keep their current value.
+	Fields map[string]string `json:"fields,omitempty"`
+
+	// ShowConfirmation: the response to the question.
+	Confirm *bool `json:"confirm,omitempty"`
+
+	// EditText: the replacement text. If nil, the text is left unchanged.
+	Text *string `json:"text,omitempty"`
+}
+
+// ReadAnswers reads a JSON list of Answers from r.
+func ReadAnswers(r io.Reader) ([]Answer, error) {
+	answers := []Answer{}
+	if err := json.NewDecoder(r).Decode(&answers); err != nil {
+		return nil, fmt.Errorf("Failed to parse answers: %w", err)
+	}
+	return answers, nil
+}
+
+// NewScripted returns a new UI that responds to each prompt with the next of
+// the answers, instead of asking the user. The dialog, including messages and
+// status updates, is written to transcript.
+// If there are no answers left, or the next answer does not fit the prompt,
+// then the prompt returns an error.
+func NewScripted(answers []Answer, transcript io.Writer) UI {
+	return &scriptedUI{answers: answers, out: transcript}
+}
+
+type scriptedUI struct {
+	answers []Answer
+	out     io.Writer
+}
+
+// next returns the next answer for the prompt of the given kind and title.
+func (u *scriptedUI) next(kind, title string) (Answer, error) {
+	fmt.Fprintf(u.out, "%v: %v\n", kind, title)
+	if len(u.answers) == 0 {
+		return Answer{}, fmt.Errorf("No scripted answer for %v '%v'", kind, title)
+	}
+	a := u.answers[0]
+	u.answers = u.answers[1:]
+	if !strings.Contains(title, a.Title) {
+		return Answer{}, fmt.Errorf("Scripted answer for '%v' does not match %v '%v'", a.Title, kind, title)
+	}
+	return a, nil
+}
+
+func (u *scriptedUI) Enter(name string, work func() error) error {
+	fmt.Fprintf(u.out, "enter: %v\n", name)
+	return work()
+}
+
+func (u *scriptedUI) ShowMenu(title string, options []string) (int, error) {
+	a, err := u.next("menu", title)
+	if err != nil {
+		return 0, err
+	}
+	for i, o := range options {
+		if o == a.Option {
+			fmt.Fprintf(u.out, "  > %v\n", o)
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("Scripted option '%v' is not one of: %v", a.Option, strings.Join(options, ", "))
+}
+
+func (u *scriptedUI) ShowForm(title string, fields []TextField) error {
+	a, err := u.next("form", title)
+	if err != nil {
+		return err
+	}
+	for name := range a.Fields {
+		found := false
+		for _, f := range fields {
+			found = found || f.Name == name
+		}
+		if !found {
+			return fmt.Errorf("Form '%v' has no field '%v'", title, name)
+		}
+	}
+	for _, f := range fields {
+		if value, ok := a.Fields[f.Name]; ok {
+			*f.Value = value
+		}
+		if err := f.validate(); err != nil {
+			return fmt.Errorf("Scripted value of field '%v' is invalid: %w", f.Name, err)
+		}
+		fmt.Fprintf(u.out, "  %v: %v\n", f.Name, f.display())
+	}
+	return nil
+}
+
+func (u *scriptedUI) ShowMessage(title, msg string, args ...interface{}) {
+	fmt.Fprintf(u.out, "message: %v\n", title)
+	for _, line := range strings.Split(fmt.Sprintf(msg, args...), "\n") {
+		fmt.Fprintf(u.out, "  %v\n", line)
+	}
+}
+
+func (u *scriptedUI) ShowConfirmation(title, msg, question string) (bool, error) {
+	u.ShowMessage(title, "%v", msg)
+	a, err := u.next("confirm", question)
+	if err != nil {
+		return false, err
+	}
+	if a.Confirm == nil {
+		return false, fmt.Errorf("Scripted answer for '%v' has no confirm response", question)
+	}
+	fmt.Fprintf(u.out, "  > %v\n", *a.Confirm)
+	return *a.Confirm, nil
+}
+
+func (u *scriptedUI) EditText(title string, text *string) error {
+	a, err := u.next("edit", title)
+	if err != nil {
+		return err
+	}
+	if a.Text != nil {
+		*text = *a.Text
+	}
+	for _, line := range strings.Split(*text, "\n") {
+		fmt.Fprintf(u.out, "  %v\n", line)
+	}
+	return nil
+}
+
+type scriptedStatus struct{ u *scriptedUI }
+
+func (s scriptedStatus) Update(msg string, args ...interface{}) {
+	fmt.Fprintf(s.u.out, "status: %v\n", fmt.Sprintf(msg, args...))
+}
+
+func (s scriptedStatus) Progress(done, total int) {
+	fmt.Fprintf(s.u.out, "progress: %d/%d\n", done, total)
+}
+
+func (u *scriptedUI) WithStatus(ctx context.Context, msg string, work func(context.Context, Status) error) error {
+	fmt.Fprintf(u.out, "status: %v\n", msg)
+	return work(ctx, scriptedStatus{u})
+}
+
+func (u *scriptedUI) Terminate() {}
@@ -0,0 +1,175 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ben-clayton/release-me/ui"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestScriptedMenu(t *testing.T) {
+	transcript := strings.Builder{}
+	u := ui.NewScripted([]ui.Answer{
+		{Title: "Select", Option: "two"},
+		{Option: "one"},
+	}, &transcript)
+
+	if i, err := u.ShowMenu("Select a number", []string{"one", "two"}); err != nil || i != 1 {
+		t.Errorf("ShowMenu() returned %v, %v, expected 1, <nil>", i, err)
+	}
+	if i, err := u.ShowMenu("Any title", []string{"one", "two"}); err != nil || i != 0 {
+		t.Errorf("ShowMenu() returned %v, %v, expected 0, <nil>", i, err)
+	}
+	if expect := "menu: Select a number\n  > two\nmenu: Any title\n  > one\n"; transcript.String() != expect {
+		t.Errorf("Transcript was:\n%v\nexpected:\n%v", transcript.String(), expect)
+	}
+}
+
+func TestScriptedForm(t *testing.T) {
+	transcript := strings.Builder{}
+	u := ui.NewScripted([]ui.Answer{
+		{Title: "Create", Fields: map[string]string{"Version": "1.2.3"}},
+	}, &transcript)
+
+	branch, version := "main", "1.0.0"
+	if err := u.ShowForm("Create new release", []ui.TextField{
+		{Name: "Main branch", Value: &branch},
+		{Name: "Version", Value: &version},
+	}); err != nil {
+		t.Fatalf("ShowForm() returned error: %v", err)
+	}
+	if branch != "main" || version != "1.2.3" {
+		t.Errorf("ShowForm() set the fields to '%v', '%v', expected 'main', '1.2.3'", branch, version)
+	}
+	if expect := "form: Create new release\n  Main branch: main\n  Version: 1.2.3\n"; transcript.String() != expect {
+		t.Errorf("Transcript was:\n%v\nexpected:\n%v", transcript.String(), expect)
+	}
+}
+
+func TestScriptedConfirmation(t *testing.T) {
+	transcript := strings.Builder{}
+	u := ui.NewScripted([]ui.Answer{
+		{Title: "Continue", Confirm: boolPtr(true)},
+		{Confirm: boolPtr(false)},
+	}, &transcript)
+
+	if ok, err := u.ShowConfirmation("Problems", "50% done", "Continue anyway"); err != nil || !ok {
+		t.Errorf("ShowConfirmation() returned %v, %v, expected true, <nil>", ok, err)
+	}
+	if ok, err := u.ShowConfirmation("Problems", "line 1\nline 2", "Continue anyway"); err != nil || ok {
+		t.Errorf("ShowConfirmation() returned %v, %v, expected false, <nil>", ok, err)
+	}
+	expect := "message: Problems\n  50% done\nconfirm: Continue anyway\n  > true\n" +
+		"message: Problems\n  line 1\n  line 2\nconfirm: Continue anyway\n  > false\n"
+	if transcript.String() != expect {
+		t.Errorf("Transcript was:\n%v\nexpected:\n%v", transcript.String(), expect)
+	}
+}
+
+func TestScriptedErrors(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		answers []ui.Answer
+		prompt  func(ui.UI) error
+		err     string
+	}{
+		{
+			name:    "title mismatch",
+			answers: []ui.Answer{{Title: "Delete", Option: "yes"}},
+			prompt: func(u ui.UI) error {
+				_, err := u.ShowMenu("Select a repo", []string{"yes"})
+				return err
+			},
+			err: "does not match menu 'Select a repo'",
+		}, {
+			name:    "unknown option",
+			answers: []ui.Answer{{Option: "three"}},
+			prompt: func(u ui.UI) error {
+				_, err := u.ShowMenu("Select a number", []string{"one", "two"})
+				return err
+			},
+			err: "Scripted option 'three' is not one of: one, two",
+		}, {
+			name:    "unknown field",
+			answers: []ui.Answer{{Fields: map[string]string{"Colour": "red"}}},
+			prompt: func(u ui.UI) error {
+				name := ""
+				return u.ShowForm("Details", []ui.TextField{{Name: "Name", Value: &name}})
+			},
+			err: "Form 'Details' has no field 'Colour'",
+		}, {
+			name:    "invalid field",
+			answers: []ui.Answer{{Fields: map[string]string{"Version": "one"}}},
+			prompt: func(u ui.UI) error {
+				version := ""
+				return u.ShowForm("Details", []ui.TextField{{
+					Name:  "Version",
+					Value: &version,
+					Validate: func(s string) error {
+						if s != "1.0.0" {
+							return errors.New("Not a version")
+						}
+						return nil
+					},
+				}})
+			},
+			err: "Scripted value of field 'Version' is invalid: Not a version",
+		}, {
+			name:    "missing confirm",
+			answers: []ui.Answer{{Option: "yes"}},
+			prompt: func(u ui.UI) error {
+				_, err := u.ShowConfirmation("Problems", "msg", "Continue anyway")
+				return err
+			},
+			err: "Scripted answer for 'Continue anyway' has no confirm response",
+		}, {
+			name:    "out of answers",
+			answers: []ui.Answer{},
+			prompt: func(u ui.UI) error {
+				_, err := u.ShowMenu("Select a repo", []string{"a"})
+				return err
+			},
+			err: "No scripted answer for menu 'Select a repo'",
+		},
+	} {
+		err := test.prompt(ui.NewScripted(test.answers, &strings.Builder{}))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%v: returned error '%v', expected error containing '%v'", test.name, err, test.err)
+		}
+	}
+}
+
+func TestReadAnswers(t *testing.T) {
+	answers, err := ui.ReadAnswers(strings.NewReader(`[
+		{"title": "Select", "option": "two"},
+		{"fields": {"Version": "1.2.3"}},
+		{"confirm": false}
+	]`))
+	if err != nil {
+		t.Fatalf("ReadAnswers() returned error: %v", err)
+	}
+	if len(answers) != 3 || answers[0].Title != "Select" || answers[0].Option != "two" ||
+		answers[1].Fields["Version"] != "1.2.3" || answers[2].Confirm == nil || *answers[2].Confirm {
+		t.Errorf("ReadAnswers() returned %+v", answers)
+	}
+	if _, err := ui.ReadAnswers(strings.NewReader(`{"option": "two"}`)); err == nil {
+		t.Errorf("ReadAnswers() did not return an error for an object")
+	}
+}
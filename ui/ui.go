@@ -15,8 +15,13 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/gdamore/tcell"
@@ -32,6 +37,8 @@ var (
 // inside a UI.WithStatus callback
 type Status interface {
 	Update(msg string, args ...interface{})
+	// Progress reports that done of total units of work have been completed.
+	Progress(done, total int)
 }
 
 // UI provides methods for an interactive user interface.
@@ -41,18 +48,72 @@ type UI interface {
 	ShowForm(title string, options []TextField) error
 	ShowMessage(title, msg string, args ...interface{})
 	ShowConfirmation(title, msg, question string) (bool, error)
-	WithStatus(msg string, work func(Status) error) error
+	// EditText presents the multi-line text for the user to review and edit.
+	// If the user cancels the edit, then text is left unchanged and an error
+	// is returned.
+	EditText(title string, text *string) error
+	// WithStatus displays the status message msg while calling work.
+	// The context passed to work is derived from ctx, and is cancelled if the
+	// user presses escape while work is running.
+	WithStatus(ctx context.Context, msg string, work func(context.Context, Status) error) error
 	Terminate()
 }
 
-// New returns a new UI.
+// New returns a new UI. If stdin and stdout are interactive terminals, then
+// a full-screen terminal UI is returned, otherwise New returns NewPlain().
 func New() UI {
+	if !isInteractive() {
+		return NewPlain()
+	}
 	s, err := tcell.NewScreen()
 	if err != nil || s == nil {
-		return stdUI{}
+		return NewPlain()
+	}
+	if err := s.Init(); err != nil {
+		return NewPlain()
+	}
+	u := &tcellUI{Screen: s, events: make(chan tcell.Event, 16)}
+	go u.pumpEvents()
+	return u
+}
+
+// NewPlain returns a new UI that writes plain lines of text to stdout, and
+// reads input from stdin.
+func NewPlain() UI {
+	return stdUI{}
+}
+
+// ciEnvVars are environment variables set by common CI systems.
+var ciEnvVars = []string{
+	"CI",
+	"CONTINUOUS_INTEGRATION",
+	"BUILD_NUMBER",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"TF_BUILD",
+	"JENKINS_URL",
+	"BUILDKITE",
+	"TEAMCITY_VERSION",
+}
+
+// isInteractive returns true if stdin and stdout are terminals capable of
+// displaying the full-screen UI, and the process is not running on CI.
+func isInteractive() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
 	}
-	s.Init()
-	return &tcellUI{Screen: s}
+	for _, v := range ciEnvVars {
+		if os.Getenv(v) != "" {
+			return false
+		}
+	}
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// isTerminal returns true if f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 // TextField holds fields of a UI text input field.
@@ -65,15 +126,43 @@ type TextField struct {
 
 	// Optional validation function for the field.
 	Validate func(string) error
+
+	// If true, the value is masked when displayed, and not echoed as typed.
+	Secret bool
+
+	// If non-empty, the field is a choice field and the value must be one of
+	// Choices. The left and right keys select the previous and next choice.
+	Choices []string
+
+	// If true, the field is a checkbox and the value must be "true" or
+	// "false". The space key toggles the value.
+	Checkbox bool
 }
 
-func (f TextField) text(highlighted bool) string {
-	if highlighted {
-		return *f.Value + "_"
+// display returns the value of the field as it should be displayed.
+func (f TextField) display() string {
+	switch {
+	case f.Secret:
+		return strings.Repeat("*", strlen(*f.Value))
+	case f.Checkbox:
+		if *f.Value == "true" {
+			return "[x]"
+		}
+		return "[ ]"
 	}
 	return *f.Value
 }
 
+func (f TextField) text(highlighted bool) string {
+	switch {
+	case !highlighted, f.Checkbox:
+		return f.display()
+	case len(f.Choices) > 0:
+		return "< " + f.display() + " >"
+	}
+	return f.display() + "_"
+}
+
 func (f TextField) color() tcell.Color {
 	if f.validate() != nil {
 		return tcell.ColorRed
@@ -82,6 +171,25 @@ func (f TextField) color() tcell.Color {
 }
 
 func (f *TextField) input(k tcell.Key, r rune) {
+	switch {
+	case f.Checkbox:
+		if r == ' ' {
+			*f.Value = strconv.FormatBool(*f.Value != "true")
+		}
+		return
+	case len(f.Choices) > 0:
+		i := f.choice()
+		switch k {
+		case tcell.KeyLeft:
+			i = (i + len(f.Choices) - 1) % len(f.Choices)
+		case tcell.KeyRight:
+			i = (i + 1) % len(f.Choices)
+		default:
+			return
+		}
+		*f.Value = f.Choices[i]
+		return
+	}
 	switch k {
 	case tcell.KeyBackspace, tcell.KeyBackspace2:
 		if r, n := utf8.DecodeLastRuneInString(*f.Value); r != utf8.RuneError {
@@ -95,7 +203,24 @@ func (f *TextField) input(k tcell.Key, r rune) {
 	}
 }
 
+// choice returns the index of the value in f.Choices, or -1 if the value is
+// not one of the choices.
+func (f TextField) choice() int {
+	for i, c := range f.Choices {
+		if c == *f.Value {
+			return i
+		}
+	}
+	return -1
+}
+
 func (f TextField) validate() error {
+	if f.Checkbox && *f.Value != "true" && *f.Value != "false" {
+		return fmt.Errorf("'%v' is not true or false", *f.Value)
+	}
+	if len(f.Choices) > 0 && f.choice() < 0 {
+		return fmt.Errorf("'%v' is not one of: %v", *f.Value, strings.Join(f.Choices, ", "))
+	}
 	if f.Validate == nil {
 		return nil
 	}
@@ -117,10 +242,16 @@ func (stdUI) ShowMenu(title string, options []string) (int, error) {
 		fmt.Printf("  (%v): %v\n", i, o)
 	}
 	for true {
-		fmt.Printf("\nEnter option [0-%d]: ", len(options)-1)
-		i := -1
-		_, err := fmt.Scan(&i)
+		fmt.Printf("\nEnter option [0-%d], or text to filter the options: ", len(options)-1)
+		in := ""
+		if _, err := fmt.Scan(&in); err != nil {
+			continue
+		}
+		i, err := strconv.Atoi(in)
 		if err != nil {
+			for _, m := range filterOptions(options, in) {
+				fmt.Printf("  (%v): %v\n", m, options[m])
+			}
 			continue
 		}
 		if i < 0 || i >= len(options) {
@@ -136,15 +267,42 @@ func (stdUI) ShowForm(title string, options []TextField) error {
 	fmt.Printf("%v", title)
 	for i, o := range options {
 		for true {
-			fmt.Printf("\n  %v: %v", o.Name, o.Value)
+			switch {
+			case o.Checkbox:
+				fmt.Printf("\n  %v: %v [y,n]: ", o.Name, o.display())
+			case len(o.Choices) > 0:
+				for j, c := range o.Choices {
+					fmt.Printf("\n    (%v): %v", j, c)
+				}
+				fmt.Printf("\n  %v: %v [0-%d]: ", o.Name, o.display(), len(o.Choices)-1)
+			default:
+				fmt.Printf("\n  %v: %v", o.Name, o.display())
+			}
 
 			in := ""
-			fmt.Scan(&in)
-			if o.Validate != nil {
-				if err := o.Validate(in); err != nil {
-					fmt.Printf("\n%v", err)
-					continue
+			if o.Secret {
+				in = readSecret()
+			} else {
+				fmt.Scan(&in)
+			}
+			switch {
+			case o.Checkbox:
+				switch strings.ToLower(in) {
+				case "y", "yes":
+					in = "true"
+				case "n", "no":
+					in = "false"
 				}
+			case len(o.Choices) > 0:
+				if j, err := strconv.Atoi(in); err == nil && j >= 0 && j < len(o.Choices) {
+					in = o.Choices[j]
+				}
+			}
+			check := o
+			check.Value = &in
+			if err := check.validate(); err != nil {
+				fmt.Printf("\n%v", err)
+				continue
 			}
 			*options[i].Value = in
 			break
@@ -163,7 +321,7 @@ func (stdUI) ShowMessage(title, msg string, args ...interface{}) {
 
 func (stdUI) ShowConfirmation(title, msg, question string) (bool, error) {
 	fmt.Printf("%s\n\n", title)
-	fmt.Printf(msg)
+	fmt.Print(msg)
 	fmt.Println()
 	for true {
 		fmt.Printf("\n%v [y,n]:", question)
@@ -179,15 +337,43 @@ func (stdUI) ShowConfirmation(title, msg, question string) (bool, error) {
 	panic("unreachable")
 }
 
-type stdStatus struct{}
+// readSecret reads a value from stdin. If stdin is a terminal, then the typed
+// characters are not echoed.
+func readSecret() string {
+	if isTerminal(os.Stdin) && setEcho(false) == nil {
+		defer fmt.Println()
+		defer setEcho(true)
+	}
+	in := ""
+	fmt.Scan(&in)
+	return in
+}
+
+type stdStatus struct {
+	start   time.Time // Time the status was entered
+	percent int       // Last printed progress percentage, or -1
+}
 
-func (stdStatus) Update(msg string, args ...interface{}) {
+func (*stdStatus) Update(msg string, args ...interface{}) {
 	fmt.Printf(msg+"\n", args...)
 }
 
-func (stdUI) WithStatus(msg string, work func(Status) error) error {
+// Progress prints a progress bar each time another 10% of the work is done.
+func (s *stdStatus) Progress(done, total int) {
+	if total <= 0 {
+		return
+	}
+	percent := done * 100 / total
+	if percent/10 == s.percent/10 && done < total {
+		return
+	}
+	s.percent = percent
+	fmt.Println(progressBar(done, total, time.Since(s.start)))
+}
+
+func (stdUI) WithStatus(ctx context.Context, msg string, work func(context.Context, Status) error) error {
 	fmt.Println(msg)
-	return work(stdStatus{})
+	return work(ctx, &stdStatus{start: time.Now(), percent: -10})
 }
 
 func (stdUI) SetStatus(msg string, args ...interface{}) {
@@ -199,7 +385,13 @@ func (stdUI) Terminate() {}
 type tcellUI struct {
 	tcell.Screen
 	status      string
+	progress    string // Progress bar displayed after the status
 	breadcrumbs []string
+
+	events        chan tcell.Event     // Events read by pumpEvents()
+	mutex         sync.Mutex           // Guards the fields below
+	awaitingInput int                  // Number of pending calls to PollEvent()
+	cancelStatus  []context.CancelFunc // Cancels the work of each active WithStatus()
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -213,20 +405,46 @@ func (u *tcellUI) Enter(name string, work func() error) error {
 	return err
 }
 
+// ShowMenu displays the options, filtering them to those that match the text
+// typed by the user.
 func (u *tcellUI) ShowMenu(title string, options []string) (int, error) {
-	selected := -1
-	err := u.drawPaged(title, len(options),
-		func(l int, highlighted bool) (string, string, tcell.Color) {
-			return options[l], "", tcell.ColorDefault
-		},
-		func(l int, k tcell.Key, r rune) (done bool) {
-			if k == tcell.KeyEnter {
-				selected = l
-				return true
-			}
-			return false
-		})
-	return selected, err
+	filter := ""
+	for {
+		matches := filterOptions(options, filter)
+		menuTitle := title
+		if filter != "" {
+			menuTitle = fmt.Sprintf("%v [filter: %v]", title, filter)
+		}
+		selected, filterChanged := -1, false
+		err := u.drawPaged(menuTitle, len(matches),
+			func(l int, highlighted bool) (string, string, tcell.Color) {
+				return options[matches[l]], "", tcell.ColorDefault
+			},
+			func(l int, k tcell.Key, r rune) (done bool) {
+				switch k {
+				case tcell.KeyEnter:
+					if l < 0 || l >= len(matches) {
+						return false
+					}
+					selected = matches[l]
+					return true
+				case tcell.KeyBackspace, tcell.KeyBackspace2:
+					if _, n := utf8.DecodeLastRuneInString(filter); n > 0 {
+						filter = filter[:len(filter)-n]
+						filterChanged = true
+						return true
+					}
+				case tcell.KeyRune:
+					filter += string(r)
+					filterChanged = true
+					return true
+				}
+				return false
+			})
+		if err != nil || !filterChanged {
+			return selected, err
+		}
+	}
 }
 
 func (u *tcellUI) ShowForm(title string, fields []TextField) error {
@@ -274,19 +492,68 @@ func (u *tcellUI) ShowForm(title string, fields []TextField) error {
 		})
 }
 
+// ShowMessage displays the message word-wrapped to the width of the screen.
+// The message can be scrolled with the arrow, page up and page down keys, and
+// searched by pressing '/', typing the search text, and pressing enter. 'n'
+// moves to the next line that matches the search.
 func (u *tcellUI) ShowMessage(title, msg string, args ...interface{}) {
-	lines := strings.Split(fmt.Sprintf(msg, args...), "\n")
-	u.drawPaged(title, len(lines),
-		func(idx int, highlighted bool) (string, string, tcell.Color) {
-			return lines[idx], "", tcell.ColorDefault
-		},
-		func(line int, key tcell.Key, r rune) (done bool) {
-			return key == tcell.KeyEnter || r == '\n'
-		})
+	w, _ := u.Size()
+	lines := wrap(fmt.Sprintf(msg, args...), w-6) // Margin, "> " prefix
+	query, searching, at := "", false, 0
+	for {
+		pageTitle := title
+		switch {
+		case searching:
+			pageTitle = fmt.Sprintf("%v [search: %v_]", title, query)
+		case query != "":
+			pageTitle = fmt.Sprintf("%v [search: %v] (n: next match)", title, query)
+		}
+		changed := false
+		err := u.drawPagedFrom(pageTitle, len(lines), at,
+			func(idx int, highlighted bool) (string, string, tcell.Color) {
+				if query != "" && containsFold(lines[idx], query) {
+					return lines[idx], "", tcell.ColorYellow
+				}
+				return lines[idx], "", tcell.ColorDefault
+			},
+			func(line int, key tcell.Key, r rune) (done bool) {
+				at = line
+				if searching {
+					switch key {
+					case tcell.KeyEnter:
+						searching, at = false, findLine(lines, query, line)
+					case tcell.KeyBackspace, tcell.KeyBackspace2:
+						if _, n := utf8.DecodeLastRuneInString(query); n > 0 {
+							query = query[:len(query)-n]
+						}
+					case tcell.KeyRune:
+						query += string(r)
+					default:
+						return false
+					}
+					changed = true
+					return true
+				}
+				switch {
+				case key == tcell.KeyEnter || r == '\n':
+					return true
+				case r == '/':
+					searching, query, changed = true, "", true
+					return true
+				case r == 'n' && query != "":
+					at, changed = findLine(lines, query, line+1), true
+					return true
+				}
+				return false
+			})
+		if err != nil || !changed {
+			return
+		}
+	}
 }
 
 func (u *tcellUI) ShowConfirmation(title, msg, question string) (bool, error) {
-	u.ShowMessage(title, msg)
+	u.ShowMessage(title, "%v", msg)
 	i, err := u.ShowMenu(question, []string{"no", "yes"})
 	if err != nil {
 		return false, err
@@ -294,32 +561,109 @@ func (u *tcellUI) ShowConfirmation(title, msg, question string) (bool, error) {
 	return i == 1, nil
 }
 
-type tcellStatus struct{ u *tcellUI }
+type tcellStatus struct {
+	u     *tcellUI
+	start time.Time // Time the status was entered
+}
 
 func (s tcellStatus) Update(msg string, args ...interface{}) {
 	s.u.status = fmt.Sprintf(msg, args...)
 	s.u.present()
 }
 
-func (u *tcellUI) WithStatus(msg string, work func(Status) error) error {
-	oldStatus := u.status
+// Progress displays a progress bar after the status message.
+func (s tcellStatus) Progress(done, total int) {
+	s.u.progress = progressBar(done, total, time.Since(s.start))
+	s.u.present()
+}
+
+func (u *tcellUI) WithStatus(ctx context.Context, msg string, work func(context.Context, Status) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	u.mutex.Lock()
+	u.cancelStatus = append(u.cancelStatus, cancel)
+	u.mutex.Unlock()
+
+	oldStatus, oldProgress := u.status, u.progress
 	u.status = msg
 	u.present()
-	err := work(tcellStatus{u})
-	u.status = oldStatus
+	err := work(ctx, tcellStatus{u: u, start: time.Now()})
+	u.status, u.progress = oldStatus, oldProgress
 	u.present()
+
+	u.mutex.Lock()
+	u.cancelStatus = u.cancelStatus[:len(u.cancelStatus)-1]
+	u.mutex.Unlock()
+
+	if err != nil && ctx.Err() == context.Canceled {
+		return ErrUserPressedEscape
+	}
 	return err
 }
 
+// PollEvent returns the next event read by pumpEvents().
+func (u *tcellUI) PollEvent() tcell.Event {
+	u.mutex.Lock()
+	u.awaitingInput++
+	u.mutex.Unlock()
+
+	ev := <-u.events
+
+	u.mutex.Lock()
+	u.awaitingInput--
+	u.mutex.Unlock()
+	return ev
+}
+
+// pumpEvents reads the screen events, forwarding them to PollEvent(). If the
+// user presses escape while a WithStatus() callback is running, and nothing is
+// waiting for input, then the callback's context is cancelled.
+func (u *tcellUI) pumpEvents() {
+	for {
+		ev := u.Screen.PollEvent()
+		if ev == nil {
+			close(u.events) // Screen finalized
+			return
+		}
+		if key, ok := ev.(*tcell.EventKey); ok && key.Key() == tcell.KeyEsc && u.cancelActiveStatus() {
+			continue
+		}
+		select {
+		case u.events <- ev:
+		default: // Event buffer is full. Drop the event.
+		}
+	}
+}
+
+// cancelActiveStatus cancels the work of the innermost active WithStatus(),
+// returning true if there was one to cancel and nothing is waiting for input.
+func (u *tcellUI) cancelActiveStatus() bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.awaitingInput > 0 || len(u.cancelStatus) == 0 {
+		return false
+	}
+	u.cancelStatus[len(u.cancelStatus)-1]()
+	return true
+}
+
 func (u *tcellUI) Terminate() { u.Fini() }
 
 func (u *tcellUI) drawPaged(title string, lines int,
 	line func(idx int, highlighted bool) (text, status string, color tcell.Color),
 	input func(line int, key tcell.Key, r rune) (done bool)) error {
+	return u.drawPagedFrom(title, lines, 0, line, input)
+}
+
+// drawPagedFrom is like drawPaged, but initially highlights the line start.
+func (u *tcellUI) drawPagedFrom(title string, lines, start int,
+	line func(idx int, highlighted bool) (text, status string, color tcell.Color),
+	input func(line int, key tcell.Key, r rune) (done bool)) error {
 
 	defer u.Clear()
 
-	highlighted, scroll := 0, 0
+	highlighted, scroll := start, 0
 	for true {
 		u.Clear()
 
@@ -370,6 +714,14 @@ func (u *tcellUI) drawPaged(title string, lines int,
 				if highlighted < lines-1 {
 					highlighted++
 				}
+			case tcell.KeyPgUp:
+				highlighted = max(highlighted-max(h, 1), 0)
+			case tcell.KeyPgDn:
+				highlighted = clamp(highlighted+max(h, 1), 0, lines-1)
+			case tcell.KeyHome:
+				highlighted = 0
+			case tcell.KeyEnd:
+				highlighted = max(lines-1, 0)
 			default:
 				if input(highlighted, event.Key(), event.Rune()) {
 					return nil
@@ -388,7 +740,11 @@ func (u *tcellUI) present() {
 	}
 	title := fmt.Sprintf("--- Release Me %v---", breadcrumbs)
 	u.SetContent(1, 1, ' ', []rune(title), tcell.StyleDefault)
-	u.SetContent(1, h-1, ' ', []rune(u.status), tcell.StyleDefault.Dim(true))
+	status := u.status
+	if u.progress != "" {
+		status += "  " + u.progress
+	}
+	u.SetContent(1, h-1, ' ', []rune(status), tcell.StyleDefault.Dim(true))
 	u.Sync()
 }
 
@@ -410,5 +766,102 @@ func clamp(x, min, max int) int {
 	}
 	return x
 }
+
+// progressBar returns a textual progress bar for done of total units of work,
+// with the percentage complete and the estimated time remaining, based on the
+// time elapsed so far.
+func progressBar(done, total int, elapsed time.Duration) string {
+	const width = 20
+	if total <= 0 {
+		return ""
+	}
+	done = clamp(done, 0, total)
+	filled := done * width / total
+	bar := fmt.Sprintf("[%v%v] %3d%%", strings.Repeat("#", filled), strings.Repeat(".", width-filled), done*100/total)
+	if done > 0 && done < total {
+		eta := elapsed * time.Duration(total-done) / time.Duration(done)
+		bar += fmt.Sprintf(" ETA %v", eta.Round(time.Second))
+	}
+	return bar
+}
+
+// wrap splits text into lines, word-wrapping lines longer than width runes.
+// Words longer than width are split.
+func wrap(text string, width int) []string {
+	out := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if width <= 0 {
+			out = append(out, line)
+			continue
+		}
+		runes := []rune(line)
+		for len(runes) > width {
+			split := width
+			for i := width; i > 0; i-- {
+				if runes[i] == ' ' {
+					split = i
+					break
+				}
+			}
+			out = append(out, strings.TrimRight(string(runes[:split]), " "))
+			runes = runes[split:]
+			for len(runes) > 0 && runes[0] == ' ' {
+				runes = runes[1:]
+			}
+		}
+		out = append(out, string(runes))
+	}
+	return out
+}
+
+// findLine returns the index of the first line at or after from that contains
+// query, ignoring case, wrapping around to the start of lines. If no line
+// contains query, then from is returned.
+func findLine(lines []string, query string, from int) int {
+	for i := range lines {
+		idx := (from + i) % len(lines)
+		if containsFold(lines[idx], query) {
+			return idx
+		}
+	}
+	return from
+}
+
+// containsFold returns true if s contains substr, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// filterOptions returns the indices of the options that match filter, ignoring
+// case. Options that contain filter are listed first, followed by those that
+// contain the characters of filter in order (fuzzy matches).
+func filterOptions(options []string, filter string) []int {
+	filter = strings.ToLower(filter)
+	exact, fuzzy := []int{}, []int{}
+	for i, o := range options {
+		o = strings.ToLower(o)
+		switch {
+		case strings.Contains(o, filter):
+			exact = append(exact, i)
+		case isSubsequence(filter, o):
+			fuzzy = append(fuzzy, i)
+		}
+	}
+	return append(exact, fuzzy...)
+}
+
+// isSubsequence returns true if the runes of sub appear in s in order.
+func isSubsequence(sub, s string) bool {
+	for _, r := range s {
+		if sub == "" {
+			return true
+		}
+		if first, n := utf8.DecodeRuneInString(sub); r == first {
+			sub = sub[n:]
+		}
+	}
+	return sub == ""
+}
+
 func align(s string, width int) string { return strings.Repeat(" ", max(width-strlen(s), 0)) + s }
 func strlen(s string) int              { return utf8.RuneCountInString(s) }
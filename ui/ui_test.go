@@ -0,0 +1,112 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterOptions(t *testing.T) {
+	options := []string{"release-1.x", "main", "Release-2.x", "feature/alpha", "mirror"}
+	for _, test := range []struct {
+		filter string
+		expect []int
+	}{
+		{"", []int{0, 1, 2, 3, 4}},
+		{"release", []int{0, 2}},
+		{"RELEASE-2", []int{2}},
+		{"mi", []int{4, 1}},    // 'mirror' contains 'mi', 'main' is a fuzzy match
+		{"rx", []int{0, 2}},    // Fuzzy matches are listed in option order
+		{"ra", []int{0, 2, 3}}, // Fuzzy matches may span words
+		{"rel2", []int{2}},
+		{"zzz", []int{}},
+	} {
+		if got := filterOptions(options, test.filter); !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("filterOptions('%v') returned %v, expected %v", test.filter, got, test.expect)
+		}
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	for _, test := range []struct {
+		done, total int
+		elapsed     time.Duration
+		expect      string
+	}{
+		{0, 10, 0, "[....................]   0%"},
+		{5, 10, 10 * time.Second, "[##########..........]  50% ETA 10s"},
+		{1, 4, 3 * time.Second, "[#####...............]  25% ETA 9s"},
+		{10, 10, time.Minute, "[####################] 100%"},
+		{15, 10, time.Minute, "[####################] 100%"}, // done > total
+		{-3, 10, time.Minute, "[....................]   0%"}, // negative done
+		{1, 0, time.Minute, ""},                              // total == 0
+		{1, -1, time.Minute, ""},                             // total < 0
+	} {
+		got := progressBar(test.done, test.total, test.elapsed)
+		if got != test.expect {
+			t.Errorf("progressBar(%v, %v, %v) returned '%v', expected '%v'",
+				test.done, test.total, test.elapsed, got, test.expect)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	for _, test := range []struct {
+		text   string
+		width  int
+		expect []string
+	}{
+		{"", 10, []string{""}},
+		{"short", 10, []string{"short"}},
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"a\nb c", 10, []string{"a", "b c"}},
+		{"abcdefghijklmnop", 5, []string{"abcde", "fghij", "klmno", "p"}}, // Long words are split
+		{"ab abcdefghij", 5, []string{"ab", "abcde", "fghij"}},            // Long word after a short one
+		{"one     two   three", 8, []string{"one", "two", "three"}},       // Runs of spaces
+		{"the quick brown fox", 0, []string{"the quick brown fox"}},       // No wrapping
+		{"the quick\nbrown fox", -1, []string{"the quick", "brown fox"}},  // No wrapping
+	} {
+		if got := wrap(test.text, test.width); !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("wrap('%v', %v) returned %q, expected %q", test.text, test.width, got, test.expect)
+		}
+	}
+}
+
+func TestFindLine(t *testing.T) {
+	lines := []string{"Release v1.2.3", "", "Fixed a bug", "Added a feature", "fixed another BUG"}
+	for _, test := range []struct {
+		query  string
+		from   int
+		expect int
+	}{
+		{"release", 0, 0},
+		{"fixed", 0, 2},
+		{"fixed", 2, 2},
+		{"fixed", 3, 4},
+		{"release", 3, 0}, // Wraps around to the start
+		{"bug", 4, 4},
+		{"BUG", 0, 2},
+		{"missing", 3, 3}, // No match returns from
+	} {
+		if got := findLine(lines, test.query, test.from); got != test.expect {
+			t.Errorf("findLine('%v', %v) returned %v, expected %v", test.query, test.from, got, test.expect)
+		}
+	}
+	if got := findLine([]string{}, "anything", 0); got != 0 {
+		t.Errorf("findLine() with no lines returned %v, expected 0", got)
+	}
+}